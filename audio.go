@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"github.com/solarlune/ldtkgo"
+
+	"gogopixel/sim"
+)
+
+const (
+	SampleRate = 44100
+	// players kept per sound effect, the oldest one restarts when they all play
+	SoundPoolSize = 4
+	// ticks of the crossfade between two music tracks
+	MusicFadeTicks = 60
+	// fall speed from which landing makes a sound
+	LandSoundSpeed = 2.0
+)
+
+// the sound effects played by the game, <assets>/sounds/<name>.wav or .ogg; the
+// missing ones are silent. The landing sounds can also be land_<material>.
+var SoundNames = []string{
+	"jump", "land", "hit", "die", "collect", "checkpoint",
+	"enemy_alert", "enemy_attack", "enemy_hurt", "enemy_die",
+}
+
+// Volumes are the volume buses, from 0 to 1: the music and sound effects
+// volumes are scaled by the master one
+type Volumes struct {
+	Master float64 `json:"master"`
+	Music  float64 `json:"music"`
+	SFX    float64 `json:"sfx"`
+}
+
+// audioStream is a decoded WAV or OGG stream
+type audioStream interface {
+	io.ReadSeeker
+	Length() int64
+}
+
+// decodeAudio decodes the WAV or OGG data, by the file extension
+func decodeAudio(path string, r io.Reader) (audioStream, error) {
+	switch filepath.Ext(path) {
+	case ".wav":
+		return wav.DecodeWithSampleRate(SampleRate, r)
+	case ".ogg":
+		return vorbis.DecodeWithSampleRate(SampleRate, r)
+	}
+	return nil, fmt.Errorf("%s: unsupported audio format", path)
+}
+
+// findAudio returns the path of the WAV or OGG file of the sound, "" if there is none
+func findAudio(dir, name string) string {
+	for _, ext := range []string{".wav", ".ogg"} {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// ----------------------------------------------------------------------------- Sound struct
+// Sound is a sound effect decoded in memory, played by a pool of voices so
+// it can overlap itself
+type Sound struct {
+	data   []byte
+	voices []*voice
+}
+
+// voice plays the sound, panned; positional voices follow the camera
+type voice struct {
+	player     *audio.Player
+	stream     *panStream
+	positional bool
+	pos        sim.Vec2D[float64]
+}
+
+func (s *Sound) voice(ctx *audio.Context) *voice {
+	for _, v := range s.voices {
+		if !v.player.IsPlaying() {
+			return v
+		}
+	}
+	if len(s.voices) < SoundPoolSize {
+		stream := newPanStream(s.data)
+		p, err := ctx.NewPlayer(stream)
+		if err != nil {
+			log.Fatal(err)
+		}
+		v := &voice{player: p, stream: stream}
+		s.voices = append(s.voices, v)
+		return v
+	}
+	// restart the oldest one
+	v := s.voices[0]
+	s.voices = append(s.voices[1:], v)
+	return v
+}
+
+// ----------------------------------------------------------------------------- musicTrack struct
+// musicTrack is a looping music streamed from its file
+type musicTrack struct {
+	name   string
+	file   *os.File
+	player *audio.Player
+	fade   float64 // 0 to 1
+	dir    float64 // fade step per tick, negative when fading out
+}
+
+func (t *musicTrack) close() {
+	t.player.Close()
+	t.file.Close()
+}
+
+// ----------------------------------------------------------------------------- Audio struct
+// Audio plays the sound effects and the music
+type Audio struct {
+	Volumes  Volumes
+	Space    SoundSpace // set with the listener
+	listener *Camera    // nil: no positional audio
+	ctx      *audio.Context
+	sounds   map[string]*Sound
+	musicDir string
+	music    *musicTrack // playing or fading in
+	previous *musicTrack // fading out
+	verbose  bool
+}
+
+// NewAudio loads the sound effects from soundDir; the music is streamed from musicDir
+func NewAudio(soundDir, musicDir string, volumes Volumes, verbose bool) *Audio {
+	a := &Audio{
+		Volumes:  volumes,
+		ctx:      audio.NewContext(SampleRate),
+		sounds:   map[string]*Sound{},
+		musicDir: musicDir,
+		verbose:  verbose,
+	}
+	for _, name := range SoundNames {
+		if path := findAudio(soundDir, name); path != "" {
+			if err := a.LoadSound(name, path); err != nil {
+				log.Fatal(err)
+			}
+		} else if verbose {
+			fmt.Println("no sound", name)
+		}
+	}
+	// the footsteps by ground material
+	for _, ext := range []string{".wav", ".ogg"} {
+		paths, _ := filepath.Glob(filepath.Join(soundDir, "land_*"+ext))
+		for _, path := range paths {
+			name := strings.TrimSuffix(filepath.Base(path), ext)
+			if _, ok := a.sounds[name]; ok {
+				continue
+			}
+			if err := a.LoadSound(name, path); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	return a
+}
+
+// LoadSound decodes the sound effect file
+func (a *Audio) LoadSound(name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stream, err := decodeAudio(path, f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	a.sounds[name] = &Sound{data: data}
+	return nil
+}
+
+// Play starts the sound effect, if it is loaded
+func (a *Audio) Play(name string) {
+	s, ok := a.sounds[name]
+	if !ok {
+		return
+	}
+	v := s.voice(a.ctx)
+	v.positional = false
+	v.stream.SetPan(0)
+	v.player.SetVolume(a.Volumes.Master * a.Volumes.SFX)
+	v.player.Rewind()
+	v.player.Play()
+}
+
+// PlayMusic crossfades to the music track, <music dir>/<name>.ogg or .wav; the
+// empty name fades the music out
+func (a *Audio) PlayMusic(name string) {
+	if a.music != nil && a.music.name == name {
+		return
+	}
+	if a.previous != nil {
+		a.previous.close()
+	}
+	a.previous = a.music
+	a.music = nil
+	if a.previous != nil {
+		a.previous.dir = -1. / MusicFadeTicks
+	}
+	if name == "" {
+		return
+	}
+	track, err := a.openMusic(name)
+	if err != nil {
+		log.Println("music:", err)
+		return
+	}
+	a.music = track
+	a.applyMusicVolume(track)
+	track.player.Play()
+}
+
+func (a *Audio) openMusic(name string) (*musicTrack, error) {
+	path := findAudio(a.musicDir, name)
+	if path == "" {
+		return nil, fmt.Errorf("%s not found in %s", name, a.musicDir)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := decodeAudio(path, f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	player, err := a.ctx.NewPlayer(audio.NewInfiniteLoop(stream, stream.Length()))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if a.verbose {
+		fmt.Println("music", path)
+	}
+	return &musicTrack{name: name, file: f, player: player, dir: 1. / MusicFadeTicks}, nil
+}
+
+func (a *Audio) applyMusicVolume(t *musicTrack) {
+	t.player.SetVolume(a.Volumes.Master * a.Volumes.Music * t.fade)
+}
+
+// Update steps the music crossfade and moves the positional sounds with the
+// camera, once per tick
+func (a *Audio) Update() {
+	a.updateVoices()
+	if t := a.music; t != nil {
+		t.fade = min(t.fade+t.dir, 1)
+		a.applyMusicVolume(t)
+	}
+	if t := a.previous; t != nil {
+		t.fade += t.dir
+		if t.fade <= 0 {
+			t.close()
+			a.previous = nil
+		} else {
+			a.applyMusicVolume(t)
+		}
+	}
+}
+
+// Subscribe plays the sounds of the player and items events
+func (a *Audio) Subscribe(events *sim.EventBus) {
+	sim.Subscribe(events, func(sim.PlayerJumped) { a.Play("jump") })
+	sim.Subscribe(events, func(e sim.PlayerLanded) {
+		if e.Speed < LandSoundSpeed {
+			return
+		}
+		// land_<material> when there is a sound for the ground tile material
+		if _, ok := a.sounds["land_"+e.Material]; ok && e.Material != "" {
+			a.Play("land_" + e.Material)
+		} else {
+			a.Play("land")
+		}
+	})
+	sim.Subscribe(events, func(sim.PlayerHit) { a.Play("hit") })
+	sim.Subscribe(events, func(sim.PlayerDied) { a.Play("die") })
+	sim.Subscribe(events, func(e sim.ItemCollected) { a.PlayAt("collect", e.Pickup.Center()) })
+	sim.Subscribe(events, func(e sim.CheckpointReached) { a.PlayAt("checkpoint", e.Checkpoint.Center()) })
+	sim.Subscribe(events, func(e sim.EnemyChanged) {
+		if name, ok := enemySounds[e.Enemy.State]; ok {
+			a.PlayAt(name, e.Enemy.Center())
+		}
+	})
+}
+
+// sounds of the enemies entering the states
+var enemySounds = map[sim.EnemyState]string{
+	sim.Enemy_Chase:  "enemy_alert",
+	sim.Enemy_Attack: "enemy_attack",
+	sim.Enemy_Hurt:   "enemy_hurt",
+	sim.Enemy_Dead:   "enemy_die",
+}
+
+// levelMusic returns the music of the level, its "music" String field
+func levelMusic(level *ldtkgo.Level) string {
+	if p := level.PropertyByIdentifier("music"); p != nil {
+		if name, ok := p.Value.(string); ok {
+			return name
+		}
+	}
+	return ""
+}
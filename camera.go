@@ -2,46 +2,55 @@ package main
 
 import (
 	"fmt"
-	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"golang.org/x/image/math/f64"
+
+	"gogopixel/sim"
 )
 
 type Camera struct {
-	X, Y, Rot, Scale float64
-	Width, Height    int
-	Surface          *ebiten.Image
-	// -------
-	Viewport f64.Vec2
-	Position f64.Vec2
+	Position      sim.Vec2D[float64] // world position at the center of the screen
+	Rot, Scale    float64
+	Width, Height int
+	Surface       *ebiten.Image
 }
 
 func NewCamera(width, height int, x, y, rotation, zoom float64) *Camera {
 	return &Camera{
-		X:        x,
-		Y:        y,
+		Position: sim.NewVec2D(x, y),
 		Width:    width,
 		Height:   height,
 		Rot:      rotation,
 		Scale:    zoom,
 		Surface:  ebiten.NewImage(width, height),
-		Viewport: f64.Vec2{0., 0.},
 	}
 }
 
 func (c *Camera) SetPosition(x, y float64) *Camera {
-	c.X = x
-	c.Y = y
+	c.Position = sim.NewVec2D(x, y)
 	return c
 }
 
 func (c *Camera) MovePosition(dx, dy float64) *Camera {
-	c.X += dx
-	c.Y += dy
+	c.Position = c.Position.Add(sim.NewVec2D(dx, dy))
 	return c
 }
 
+// half the screen size: the screen position of the camera Position
+func (c *Camera) center() sim.Vec2D[float64] {
+	return sim.NewVec2D(c.Width, c.Height).Float().Scale(0.5)
+}
+
+// GeoM is the world to screen transform, the same as WorldToScreenCoords
+func (c *Camera) GeoM() ebiten.GeoM {
+	g := ebiten.GeoM{}
+	g.Translate(-c.Position.X, -c.Position.Y)
+	g.Rotate(c.Rot)
+	g.Scale(c.Scale, c.Scale)
+	g.Translate(c.center().XY())
+	return g
+}
+
 func (c *Camera) Blit(screen *ebiten.Image) {
 	op := &ebiten.DrawImageOptions{}
 	w, h := c.Surface.Bounds().Dx(), c.Surface.Bounds().Dy()
@@ -63,53 +72,22 @@ func (c *Camera) Info() {
 	//fmt.Println("--------------------------------------------")
 }
 
-// get screen coords from world coords
-func (c *Camera) WorldToScreenCoords(x, y float64) (float64, float64) {
-	// Extracts the width and height of the camera's viewport.
-	w, h := c.Width, c.Height
-	// Calculates the cosine and sine of the camera's rotation angle Rot. This will be used to rotate the coordinates.
-	co := math.Cos(c.Rot)
-	si := math.Sin(c.Rot)
-
-	// Translates the given world coordinates by subtracting the camera's position X and Y.
-	// This makes the camera the origin in the world space.
-	x, y = x-c.X, y-c.Y
-	// Rotates the translated coordinates using rotation matrices. This applies the camera's rotation to the coordinates.
-	x, y = co*x-si*y, si*x+co*y
-
-	// Scales the rotated coordinates by the camera's scale Scale and
-	// then translates them to the center of the screen space
-	// (adding half of the viewport width and height).
-	// This effectively maps the rotated, scaled, and translated world coordinates to the screen coordinates.
-	return x*c.Scale + float64(w)/2, y*c.Scale + float64(h)/2
+// WorldToScreen gets the screen position of a world position: relative to the
+// camera, rotated, scaled, then moved to the center of the screen
+func (c *Camera) WorldToScreen(v sim.Vec2D[float64]) sim.Vec2D[float64] {
+	return v.Sub(c.Position).Rotate(c.Rot).Scale(c.Scale).Add(c.center())
 }
 
-func (c *Camera) ScreenToWorldCoords(x, y float64) (float64, float64) {
-	// Extracts the width and height of the camera's viewport.
-	w, h := c.Width, c.Height
-	// Calculate the cosine and sine of the negative of the camera's rotation angle.
-	// Negative rotation is used to reverse the effect of rotation on the coordinates.
-	co := math.Cos(-c.Rot)
-	si := math.Sin(-c.Rot)
-
-	// Translate the coordinates to the center of the viewport and scale them
-	// according to the camera's scale.
-	x, y = (x-float64(w)/2)/c.Scale, (y-float64(h)/2)/c.Scale
-	x, y = co*x-si*y, si*x+co*y
-
-	return x + c.X, y + c.Y
+// ScreenToWorld is the reverse of WorldToScreen
+func (c *Camera) ScreenToWorld(v sim.Vec2D[float64]) sim.Vec2D[float64] {
+	return v.Sub(c.center()).Scale(1 / c.Scale).Rotate(-c.Rot).Add(c.Position)
 }
 
-/*
-func (c *Camera) Reset() {
-	c.Position[0] = 0.
-	c.Position[1] = 0.
+// get screen coords from world coords
+func (c *Camera) WorldToScreenCoords(x, y float64) (float64, float64) {
+	return c.WorldToScreen(sim.NewVec2D(x, y)).XY()
 }
 
-func (c *Camera) viewportCenter() f64.Vec2 {
-	return f64.Vec2{
-		c.Viewport[0] * 0.5,
-		c.Viewport[1] * 0.5,
-	}
+func (c *Camera) ScreenToWorldCoords(x, y float64) (float64, float64) {
+	return c.ScreenToWorld(sim.NewVec2D(x, y)).XY()
 }
-*/
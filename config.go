@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------- Config struct
+// Config holds the startup settings: defaults, overridden by the config file,
+// overridden by the command-line flags.
+type Config struct {
+	Title       string      `json:"title"`
+	Width       int         `json:"width"`  // window width
+	Height      int         `json:"height"` // window height
+	Fullscreen  bool        `json:"fullscreen"`
+	VSync       bool        `json:"vsync"`
+	LogicalW    int         `json:"logical_width"` // fixed game resolution
+	LogicalH    int         `json:"logical_height"`
+	ScaleMode   string      `json:"scale_mode"` // integer, fit or fill
+	AssetRoot   string      `json:"asset_root"`
+	Map         string      `json:"map"`   // LDtk project, relative to AssetRoot
+	Level       string      `json:"level"` // starting level identifier, empty for the first level
+	Character   string      `json:"character"`
+	SaveSlot    int         `json:"save_slot"`
+	Continue    bool        `json:"continue"` // start from the save slot when there is one
+	Audio       Volumes     `json:"audio"`
+	Transitions Transitions `json:"transitions"`
+	PostFX      []PostPass  `json:"postfx"` // the post-processing passes, in order
+	Debug       Debug       `json:"debug"`
+	// sim.PlayerPhysics fields overriding the character manifest ones
+	Physics json.RawMessage `json:"physics"`
+}
+
+// Transitions are the screen transitions: fade, wipe, diagonal_wipe or iris
+type Transitions struct {
+	Door  string `json:"door"`
+	Death string `json:"death"`
+	Ticks int    `json:"ticks"` // of each half, covering and uncovering
+}
+
+type Debug struct {
+	FPS      bool `json:"fps"`      // show TPS/FPS
+	Hitboxes bool `json:"hitboxes"` // draw the player hitbox
+	Verbose  bool `json:"verbose"`  // print project and level loading info
+	Cursor   bool `json:"cursor"`   // show the cursor world position
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Title:     "Goblit",
+		Width:     ScreenW,
+		Height:    ScreenH,
+		VSync:     true,
+		LogicalW:  LogicalW,
+		LogicalH:  LogicalH,
+		ScaleMode: ScaleInteger.String(),
+		AssetRoot: "assets",
+		Map:       "map/map1.ldtk",
+		Character: "Pink Man",
+		Continue:  true,
+		Audio:     Volumes{Master: 1, Music: 0.8, SFX: 1},
+		Transitions: Transitions{
+			Door:  Transition_Iris.String(),
+			Death: Transition_Fade.String(),
+			Ticks: TransitionTicks,
+		},
+	}
+}
+
+// DefaultConfigPath is <user config dir>/gogopixel/config.json
+func DefaultConfigPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "gogopixel", "config.json")
+}
+
+// LoadConfig reads the config file named by -config (or the default one, if it
+// exists) and then applies the command-line flags on top of it.
+func LoadConfig(args []string) (*Config, error) {
+	// first pass only to find the config file
+	cfg := DefaultConfig()
+	path, err := parseFlags(cfg, args)
+	if err != nil {
+		return nil, err
+	}
+	explicit := path != ""
+	if !explicit {
+		path = DefaultConfigPath()
+	}
+
+	cfg = DefaultConfig()
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			if explicit || !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			path = ""
+		}
+	}
+	if _, err := parseFlags(cfg, args); err != nil {
+		return nil, err
+	}
+	if path != "" && cfg.Debug.Verbose {
+		fmt.Println("Config file", path)
+	}
+	return cfg, cfg.validate()
+}
+
+func (cfg *Config) readFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+// parseFlags binds the flags to cfg (so the current values are the defaults)
+// and returns the -config path
+func parseFlags(cfg *Config, args []string) (string, error) {
+	var path string
+	fs := flag.NewFlagSet("gogopixel", flag.ContinueOnError)
+	fs.StringVar(&path, "config", "", "config file (default "+DefaultConfigPath()+")")
+	fs.StringVar(&cfg.Title, "title", cfg.Title, "window title")
+	fs.IntVar(&cfg.Width, "width", cfg.Width, "window width")
+	fs.IntVar(&cfg.Height, "height", cfg.Height, "window height")
+	fs.BoolVar(&cfg.Fullscreen, "fullscreen", cfg.Fullscreen, "start in fullscreen")
+	fs.BoolVar(&cfg.VSync, "vsync", cfg.VSync, "enable vsync")
+	fs.IntVar(&cfg.LogicalW, "logical-width", cfg.LogicalW, "game resolution width")
+	fs.IntVar(&cfg.LogicalH, "logical-height", cfg.LogicalH, "game resolution height")
+	fs.StringVar(&cfg.ScaleMode, "scale-mode", cfg.ScaleMode, "scaling to the window: integer, fit or fill")
+	fs.StringVar(&cfg.AssetRoot, "assets", cfg.AssetRoot, "asset root directory")
+	fs.StringVar(&cfg.Map, "map", cfg.Map, "LDtk project, relative to the asset root")
+	fs.StringVar(&cfg.Level, "level", cfg.Level, "starting level identifier")
+	fs.StringVar(&cfg.Character, "character", cfg.Character, "player character (folder in <assets>/hero)")
+	fs.IntVar(&cfg.SaveSlot, "slot", cfg.SaveSlot, "save slot")
+	fs.BoolVar(&cfg.Continue, "continue", cfg.Continue, "continue from the save slot")
+	fs.Float64Var(&cfg.Audio.Master, "volume", cfg.Audio.Master, "master volume, 0 to 1")
+	fs.Float64Var(&cfg.Audio.Music, "music-volume", cfg.Audio.Music, "music volume, 0 to 1")
+	fs.Float64Var(&cfg.Audio.SFX, "sfx-volume", cfg.Audio.SFX, "sound effects volume, 0 to 1")
+	fs.StringVar(&cfg.Transitions.Door, "door-transition", cfg.Transitions.Door, "door transition: fade, wipe, diagonal_wipe or iris")
+	fs.StringVar(&cfg.Transitions.Death, "death-transition", cfg.Transitions.Death, "death transition")
+	fs.IntVar(&cfg.Transitions.Ticks, "transition-ticks", cfg.Transitions.Ticks, "ticks of each half of the transitions")
+	fs.Func("postfx", "post-processing shaders, in order, e.g. crt,vignette ("+strings.Join(ShaderNames(), ", ")+"; empty for none)", func(s string) error {
+		passes, err := ParsePostPasses(s, cfg.PostFX)
+		if err == nil {
+			cfg.PostFX = passes
+		}
+		return err
+	})
+	fs.BoolVar(&cfg.Debug.FPS, "debug-fps", cfg.Debug.FPS, "show TPS/FPS")
+	fs.BoolVar(&cfg.Debug.Hitboxes, "debug-hitboxes", cfg.Debug.Hitboxes, "draw hitboxes")
+	fs.BoolVar(&cfg.Debug.Verbose, "debug-verbose", cfg.Debug.Verbose, "print loading info")
+	fs.BoolVar(&cfg.Debug.Cursor, "debug-cursor", cfg.Debug.Cursor, "show the cursor world position")
+	err := fs.Parse(args)
+	return path, err
+}
+
+func (cfg *Config) validate() error {
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return fmt.Errorf("config: invalid window size %dx%d", cfg.Width, cfg.Height)
+	}
+	if cfg.LogicalW <= 0 || cfg.LogicalH <= 0 {
+		return fmt.Errorf("config: invalid logical resolution %dx%d", cfg.LogicalW, cfg.LogicalH)
+	}
+	if _, err := ParseScaleMode(cfg.ScaleMode); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	for _, name := range []string{cfg.Transitions.Door, cfg.Transitions.Death} {
+		if _, err := ParseTransition(name); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	if cfg.Transitions.Ticks <= 0 {
+		return fmt.Errorf("config: invalid transition ticks %d", cfg.Transitions.Ticks)
+	}
+	for _, p := range cfg.PostFX {
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	for _, v := range []float64{cfg.Audio.Master, cfg.Audio.Music, cfg.Audio.SFX} {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("config: invalid volume %g", v)
+		}
+	}
+	return nil
+}
+
+// AssetPath joins a path relative to the asset root
+func (cfg *Config) AssetPath(path string) string {
+	return filepath.Join(cfg.AssetRoot, path)
+}
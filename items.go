@@ -0,0 +1,114 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"gogopixel/sim"
+)
+
+// ----------------------------------------------------------------------------- Progress struct
+// Progress is what the player achieved over all the levels: the score, the
+// collected pickups and the activated checkpoints. The ids are qualified by the
+// level identifier ("Level_0/apple_1") so they are unique in the saves.
+type Progress struct {
+	Score       int
+	Collected   []string
+	Checkpoints []string
+}
+
+func levelItemID(level, id string) string {
+	return level + "/" + id
+}
+
+// inLevel returns the ids of the level (unqualified)
+func inLevel(ids []string, level string) []string {
+	found := []string{}
+	for _, id := range ids {
+		if rest, ok := strings.CutPrefix(id, level+"/"); ok {
+			found = append(found, rest)
+		}
+	}
+	return found
+}
+
+// Restore restores the progress of the world level
+func (pr *Progress) Restore(w *sim.World) {
+	level := w.Level.Identifier
+	w.Restore(inLevel(pr.Collected, level), inLevel(pr.Checkpoints, level))
+}
+
+// Subscribe records what is collected and activated in the worlds
+func (pr *Progress) Subscribe(events *sim.EventBus) {
+	sim.Subscribe(events, func(e sim.ItemCollected) {
+		pr.Score += e.Pickup.Value
+		pr.Collected = append(pr.Collected, levelItemID(e.Level, e.Pickup.ID))
+	})
+	sim.Subscribe(events, func(e sim.CheckpointReached) {
+		pr.Checkpoints = append(pr.Checkpoints, levelItemID(e.Level, e.Checkpoint.ID))
+	})
+}
+
+// ----------------------------------------------------------------------------- items drawing
+// plain shapes, there are no fruit sprites yet
+var fruitColors = map[string]color.RGBA{
+	"apple":      {0xe0, 0x30, 0x30, 0xff},
+	"bananas":    {0xf0, 0xd0, 0x30, 0xff},
+	"cherries":   {0xc0, 0x10, 0x40, 0xff},
+	"kiwi":       {0x70, 0xa0, 0x30, 0xff},
+	"melon":      {0x40, 0xc0, 0x60, 0xff},
+	"orange":     {0xf0, 0x90, 0x20, 0xff},
+	"pineapple":  {0xe0, 0xb0, 0x40, 0xff},
+	"strawberry": {0xf0, 0x40, 0x60, 0xff},
+}
+
+// fruitColor returns the color of the fruit kind, apple red for the unknown ones
+func fruitColor(kind string) color.RGBA {
+	if clr, ok := fruitColors[strings.ToLower(kind)]; ok {
+		return clr
+	}
+	return fruitColors["apple"]
+}
+
+// ticks of the collected effect
+const CollectedEffectTicks = 20
+
+// DrawItems draws the pickups (bobbing), their collected effect and the checkpoints
+func (er *Renderer) DrawItems(screen *ebiten.Image, w *sim.World) {
+	cam := er.camera
+	for _, c := range w.Checkpoints {
+		x, y := cam.WorldToScreen(c.Pos()).XY()
+		pw, ph := c.Size().Scale(cam.Scale).XY()
+		clr := color.RGBA{0x80, 0x80, 0x80, 0xff}
+		if c.Active {
+			clr = color.RGBA{0x30, 0xd0, 0x50, 0xff}
+		}
+		// pole and flag
+		vector.DrawFilledRect(screen, float32(x+pw/2-1), float32(y), 2, float32(ph), color.RGBA{0xd0, 0xd0, 0xd0, 0xff}, false)
+		vector.DrawFilledRect(screen, float32(x+pw/2+1), float32(y), float32(pw/2), float32(ph/3), clr, false)
+	}
+
+	for _, p := range w.Pickups {
+		center := cam.WorldToScreen(p.Center())
+		cx, cy := float32(center.X), float32(center.Y)
+		r := float32(math.Min(p.W, p.H) / 3 * cam.Scale)
+		if !p.Collected {
+			clr := fruitColor(p.Kind)
+			bob := float32(math.Sin(float64(w.Tick)/10+p.X) * 2 * cam.Scale)
+			vector.DrawFilledCircle(screen, cx, cy+bob, r, clr, true)
+			continue
+		}
+		age := w.Tick - p.CollectedAt
+		if p.CollectedAt < 0 || age >= CollectedEffectTicks {
+			continue
+		}
+		// expanding, fading ring
+		f := float32(age) / CollectedEffectTicks
+		alpha := uint8(255 * (1 - f))
+		vector.StrokeCircle(screen, cx, cy, r*(1+2*f), 1.5, color.RGBA{alpha, alpha, alpha, alpha}, true)
+	}
+}
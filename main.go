@@ -1,67 +1,169 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	_ "image/png"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/solarlune/ldtkgo"
+
+	"gogopixel/save"
+	"gogopixel/sim"
 )
 
+// default window size and game resolution
 const (
-	ScreenW = 1280
-	ScreenH = 720
+	ScreenW  = 1280
+	ScreenH  = 720
+	LogicalW = ScreenW / 2
+	LogicalH = ScreenH / 2
+	// ticks of each half of the screen transitions, covering and uncovering
+	TransitionTicks = 30
+	// width of a character of the debug font
+	DebugCharW = 6
 )
 
 // -------------------------------------------------------------
 type Game struct {
+	world          *sim.World
 	player         *Player
 	LDTKProject    *ldtkgo.Project
+	LDTKDefs       *sim.Defs
+	physics        sim.PlayerPhysics
+	progress       Progress
+	events         *sim.EventBus // shared by the worlds of all the levels
+	tweens         *sim.Tweens   // stepped by Update, they pause with the game
+	audio          *Audio
+	particles      *Particles
+	postfx         *PostFX
+	effects        *PlayerEffects
 	EbitenRenderer *Renderer
 	CurrentLevel   int
 	time           int64
 	camera         *Camera
+	viewport       *Viewport
+	saves          *save.Store
+	SaveSlot       int
+	config         *Config
+	// screen transition, pausing the world or not, and the ones to run next
+	transition *Transition
+	queued     []*Transition
+	// trigger zones
+	message     string
+	messageX    float64
+	messageAnim sim.Animator
+	cutscene    string
 }
 
-func NewGame() *Game {
+func NewGame(cfg *Config) *Game {
 
-	cam := NewCamera(ScreenW, ScreenH, 0, 0, 0, 1.0)
-	cam.Info()
+	// the camera is centered on the world position it looks at
+	cam := NewCamera(cfg.LogicalW, cfg.LogicalH, float64(cfg.LogicalW)/2, float64(cfg.LogicalH)/2, 0, 1.0)
+	if cfg.Debug.Verbose {
+		cam.Info()
+	}
 
+	mode, _ := ParseScaleMode(cfg.ScaleMode) // validated by LoadConfig
 	g := &Game{
-		player: NewPlayer(),
-		camera: cam,
+		camera:   cam,
+		viewport: NewViewport(cfg.LogicalW, cfg.LogicalH, mode),
+		config:   cfg,
+		SaveSlot: cfg.SaveSlot,
+		events:   sim.NewEventBus(),
+		tweens:   sim.NewTweens(),
 	}
+	g.progress.Subscribe(g.events)
+	g.subscribeZones()
+	g.subscribeTransitions()
 
 	var err error
-	g.LDTKProject, err = ldtkgo.Open("assets/map/map1.ldtk")
+	g.LDTKProject, err = ldtkgo.Open(cfg.AssetPath(cfg.Map))
+	if err != nil {
+		panic(err)
+	}
+	g.LDTKDefs, err = sim.OpenDefs(cfg.AssetPath(cfg.Map))
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("LTDK JSON ver = %s\n", g.LDTKProject.JSONVersion)
+	if cfg.Debug.Verbose {
+		fmt.Printf("LTDK JSON ver = %s\n", g.LDTKProject.JSONVersion)
 
-	fmt.Println("--- Tilesets")
-	for i, tileset := range g.LDTKProject.Tilesets {
-		fmt.Printf("%d: %d - Tileset id = %s - path = %s\n", i, tileset.ID, tileset.Identifier, tileset.Path)
+		fmt.Println("--- Tilesets")
+		for i, tileset := range g.LDTKProject.Tilesets {
+			fmt.Printf("%d: %d - Tileset id = %s - path = %s\n", i, tileset.ID, tileset.Identifier, tileset.Path)
+		}
+		fmt.Println("--- Levels")
+		for i, level := range g.LDTKProject.Levels {
+			fmt.Printf("%d: level %s\n", i, level.Identifier)
+		}
 	}
-	fmt.Println("--- Levels")
-	for i, level := range g.LDTKProject.Levels {
-		fmt.Printf("%d: level %s\n", i, level.Identifier)
+	g.EbitenRenderer = NewRenderer(NewDiskLoader(cfg.AssetRoot), cam)
+	g.audio = NewAudio(cfg.AssetPath("sounds"), cfg.AssetPath("music"), cfg.Audio, cfg.Debug.Verbose)
+	g.audio.SetListener(cam)
+	g.audio.Subscribe(g.events)
+	sim.Subscribe(g.events, func(e sim.LevelEntered) {
+		g.audio.PlayMusic(levelMusic(g.LDTKProject.Levels[e.Index]))
+	})
+	g.particles = NewParticles()
+	g.postfx = NewPostFX(cfg.PostFX)
+	g.effects = NewPlayerEffects(g.particles)
+	g.effects.Subscribe(g.events, func() *sim.World { return g.world })
+
+	g.physics, err = LoadPhysics(g.characterDir(), cfg.Physics)
+	if err != nil {
+		log.Fatal(err)
 	}
-	g.CurrentLevel = 0
 
-	g.EbitenRenderer = NewRenderer(NewDiskLoader(""), cam)
-	g.EbitenRenderer.Load(g.LDTKProject.Levels[g.CurrentLevel])
+	g.saves, err = save.DefaultStore()
+	if err != nil {
+		log.Println("saves disabled:", err)
+	}
+	// continue from the save slot if there is one
+	if !cfg.Continue || g.saves == nil || g.LoadGame() != nil {
+		g.LoadLevel(g.startLevel())
+	}
 
 	g.time = 0
 
 	return g
 }
 
+// LoadLevel (re)starts the level at the given index
+func (g *Game) LoadLevel(index int) {
+	g.CurrentLevel = index
+	level := g.LDTKProject.Levels[g.CurrentLevel]
+	g.world = sim.NewWorld(level, g.LDTKDefs)
+	g.EbitenRenderer.Load(level, g.world.Tiles)
+	g.world.Player.Physics = g.physics
+	g.world.Events = g.events
+	g.progress.Restore(g.world)
+	g.player = NewPlayer(g.world.Player, g.characterDir())
+	g.events.Publish(sim.LevelEntered{Level: level.Identifier, Index: index})
+}
+
+func (g *Game) characterDir() string {
+	return g.config.AssetPath(filepath.Join("hero", g.config.Character))
+}
+
+// index of the starting level set in the config (the first level by default)
+func (g *Game) startLevel() int {
+	if g.config.Level == "" {
+		return 0
+	}
+	index := g.levelIndex(g.config.Level)
+	if index < 0 {
+		log.Fatalf("level %q not found", g.config.Level)
+	}
+	return index
+}
+
 /*
 // repeatingKeyPressed return true when key is pressed considering the repeat state.
 func repeatingKeyPressed(key ebiten.Key) bool {
@@ -92,52 +194,90 @@ func (g *Game) Update() error {
 		//g.EbitenRenderer.MoveCamera(-5, 0)
 	}
 	// --- move player
-	if ebiten.IsKeyPressed(ebiten.KeyRight) {
-		g.player.Move(1.0, 0.0)
-		//g.EbitenRenderer.MoveCamera(1, 0)
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyLeft) {
-		g.player.Move(-1.0, 0.0)
+	in := sim.Input{
+		Left:  ebiten.IsKeyPressed(ebiten.KeyLeft),
+		Right: ebiten.IsKeyPressed(ebiten.KeyRight),
+		Up:    ebiten.IsKeyPressed(ebiten.KeyUp),
+		Down:  ebiten.IsKeyPressed(ebiten.KeyDown),
+		Jump:  ebiten.IsKeyPressed(ebiten.KeySpace),
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyUp) {
-		g.player.Move(0., -1.)
+	if !g.worldPaused() {
+		g.world.Update(in)
+		g.effects.Update(g.world.Player)
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyDown) {
-		g.player.Move(0., 1.)
-	}
-	/*
-		if repeatingKeyPressed(ebiten.KeyRight) {
-			g.player.Move(1.0, 0)
+	g.tweens.Update()
+	g.particles.Update()
+	g.updateTransition()
+
+	// --- quick save / load
+	if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+		if err := g.SaveGame(); err != nil {
+			log.Println("save failed:", err)
 		}
-		if repeatingKeyPressed(ebiten.KeyLeft) {
-			g.player.Move(-1.0, 0)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF9) {
+		if err := g.LoadGame(); err != nil {
+			log.Println("load failed:", err)
 		}
-	*/
-
-	if inpututil.IsKeyJustPressed(ebiten.KeyA) {
-		g.player.CycleAnim()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		g.postfx.Enabled = !g.postfx.Enabled
 	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
 		os.Exit(0)
 	}
 	g.player.Update()
+	g.EbitenRenderer.Update()
+	g.events.Flush()
+	g.audio.Update()
 	g.time += 1
 
 	return nil
 }
 
-func (g *Game) Draw(screen *ebiten.Image) {
+func (g *Game) Draw(window *ebiten.Image) {
+	// the game is drawn at the logical resolution, then scaled to the window
+	screen := g.viewport.Canvas
+	screen.Clear()
+	defer func() { g.viewport.Draw(window, g.postfx.Apply(screen)) }()
 
 	//g.RenderLevel(screen)
 	level := g.LDTKProject.Levels[g.CurrentLevel]
 	g.EbitenRenderer.Render(screen, level)
+	g.EbitenRenderer.DrawPlatforms(screen, g.world.Platforms)
+	g.EbitenRenderer.DrawEnemies(screen, g.world.Enemies)
+	g.EbitenRenderer.DrawItems(screen, g.world)
+	g.particles.Draw(screen, g.camera)
 
-	g.player.Draw(screen)
+	g.player.Draw(screen, g.camera)
 
 	//screen.Fill(color.RGBA{0x33, 0x33, 0x33, 0xff})
 	if (g.time / 60) > 5.0 {
 		ebitenutil.DebugPrint(screen, "Ebiten Engine (after 5 sec)")
 	}
+	if g.message != "" {
+		ebitenutil.DebugPrintAt(screen, g.message, int(g.messageX), screen.Bounds().Dy()-32)
+	}
+	collected, total := g.world.Progress()
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Score %d  Fruits %d/%d", g.progress.Score, collected, total), 0, screen.Bounds().Dy()-16)
+	if g.config.Debug.FPS {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS %0.1f FPS %0.1f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 16)
+	}
+	if g.config.Debug.Hitboxes {
+		g.player.DrawHitbox(screen, g.camera)
+	}
+	if g.config.Debug.Cursor {
+		if x, y, ok := g.CursorWorldPosition(); ok {
+			ebitenutil.DebugPrintAt(screen, fmt.Sprintf("cursor %0.1f,%0.1f", x, y), 0, 32)
+			if m := g.EbitenRenderer.TileAt(sim.NewVec2D(x, y)); m != nil {
+				ebitenutil.DebugPrintAt(screen, fmt.Sprintf("tile %d %v %s", m.ID, m.Tags, m.Raw), 0, 48)
+			}
+		}
+	}
+	// last, over the HUD and the debug readouts
+	if g.transition != nil {
+		g.transition.Draw(screen)
+	}
 	/*
 		for _, layer := range g.EbitenRenderer.RenderedLayers {
 			fmt.Println("draw layer ", layer.Layer.Identifier)
@@ -153,7 +293,18 @@ func (g *Game) RenderLevel(screen *ebiten.Image) {
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return ScreenW / 2, ScreenH / 2
+	return g.viewport.Layout(outsideWidth, outsideHeight)
+}
+
+// CursorWorldPosition returns the mouse cursor position in the world; false when
+// the cursor is on the letterbox bars
+func (g *Game) CursorWorldPosition() (float64, float64, bool) {
+	x, y, ok := g.viewport.ScreenToLogical(ebiten.CursorPosition())
+	if !ok {
+		return 0, 0, false
+	}
+	x, y = g.camera.ScreenToWorldCoords(x, y)
+	return x, y, true
 }
 
 /*
@@ -174,9 +325,19 @@ func bytes2Image(rawImage *[]byte) image.Image {
 */
 
 func main() {
-	ebiten.SetWindowSize(ScreenW, ScreenH)
-	ebiten.SetWindowTitle("Goblit")
-	if err := ebiten.RunGame(NewGame()); err != nil {
+	cfg, err := LoadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ebiten.SetWindowSize(cfg.Width, cfg.Height)
+	ebiten.SetWindowTitle(cfg.Title)
+	ebiten.SetFullscreen(cfg.Fullscreen)
+	ebiten.SetVsyncEnabled(cfg.VSync)
+	if err := ebiten.RunGame(NewGame(cfg)); err != nil {
 		log.Fatal(err)
 	}
 }
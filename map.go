@@ -11,7 +11,10 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/solarlune/ldtkgo"
+
+	"gogopixel/sim"
 )
 
 type TilesetLoader interface {
@@ -54,6 +57,12 @@ type Renderer struct {
 	Loader         TilesetLoader
 	camera         *Camera
 	Buffer         *ebiten.Image
+	// tiles metadata of the level, shared with the world
+	Tiles *sim.LevelTiles
+	// animated tiles by tileset path and tile ID, and the ones of the level
+	Animations    map[string]map[int]*TileAnimation
+	AnimatedTiles []*AnimatedTile
+	Tick          int64 // animation time, stepped by Update
 }
 
 func NewRenderer(loader TilesetLoader, cam *Camera) *Renderer {
@@ -70,6 +79,7 @@ func NewRenderer(loader TilesetLoader, cam *Camera) *Renderer {
 	return &Renderer{
 		Tilesets:       map[string]*ebiten.Image{},
 		RenderedLayers: []*RenderedLayer{},
+		Animations:     map[string]map[int]*TileAnimation{},
 		Loader:         loader,
 		camera:         cam,
 	}
@@ -90,7 +100,9 @@ func (er *EbitenRenderer) beginLayer(layer *ldtkgo.Layer, w, h int) {
 }
 */
 
-func (er *Renderer) Load(level *ldtkgo.Level) {
+// Load loads the tilesets of the level and renders its static layers; tiles is
+// the metadata of the level tiles, shared with the world
+func (er *Renderer) Load(level *ldtkgo.Level, tiles *sim.LevelTiles) {
 
 	fmt.Println("---------------- Loading -------------------")
 	fmt.Printf("LEVEL \tWidth=%d - Height=%d\n", level.Width, level.Height)
@@ -105,17 +117,18 @@ func (er *Renderer) Load(level *ldtkgo.Level) {
 		_, exists := er.Tilesets[layer.Tileset.Path]
 		if !exists {
 			// LDTK store external assets filenames with relative syntax
-			newPath := "assets/" + strings.Replace(layer.Tileset.Path, "../", "", -1)
-			fmt.Printf("Loading Tileset (path = %s)\n", newPath)
-			tileimg, _, err := ebitenutil.NewImageFromFile(newPath)
-			if err != nil {
-				log.Fatal(err)
+			newPath := strings.Replace(layer.Tileset.Path, "../", "", -1)
+			tileimg := er.Loader.LoadTileset(newPath)
+			if tileimg == nil {
+				log.Fatalf("cannot load tileset %s", newPath)
 			}
 			er.Tilesets[layer.Tileset.Path] = tileimg
 		}
 	}
 
 	er.Offscreen = ebiten.NewImage(level.Width, level.Height)
+	er.AnimatedTiles = []*AnimatedTile{}
+	er.Tiles = tiles
 	// only to test image buffer size
 	er.Buffer = ebiten.NewImage(4096*2, 4096*2)
 	fmt.Printf("buffer w=%d h=%d\n", er.Buffer.Bounds().Dx(), er.Buffer.Bounds().Dy())
@@ -157,28 +170,22 @@ func (er *Renderer) RenderOffscreen(level *ldtkgo.Level) {
 			//opacity := 1.0
 			if tiles := layer.AllTiles(); len(tiles) > 0 {
 				for _, tileData := range tiles {
+					alpha := float32(1)
+					if i == 1 {
+						alpha = 0.4
+					}
+					// animated tiles are drawn over the static ones every frame
+					if anim := er.animation(layer.Tileset, tileData.ID); anim != nil {
+						er.AnimatedTiles = append(er.AnimatedTiles, &AnimatedTile{Tile: tileData, Layer: layer, Animation: anim, Alpha: alpha})
+						continue
+					}
 					//fmt.Printf("%d-", tileData.ID)
-					tilex := tileData.Position[0]
-					tiley := tileData.Position[1]
-					//fmt.Printf("tile x=%d y=%d\n", tilex, tiley)
 					//rect := image.Rect(tilex, tiley, tilex+16, tiley+16)
 					rect := image.Rect(tileData.Src[0], tileData.Src[1], tileData.Src[0]+layer.GridSize, tileData.Src[1]+layer.GridSize)
 					tileimg := tileimg.SubImage(rect).(*ebiten.Image)
 
-					opt := &ebiten.DrawImageOptions{}
-					opt.GeoM.Translate(float64(-layer.GridSize/2), float64(-layer.GridSize/2))
-					if tileData.FlipX() {
-						opt.GeoM.Scale(-1, 1)
-					}
-					if tileData.FlipY() {
-						opt.GeoM.Scale(1, -1)
-					}
-					opt.GeoM.Translate(float64(layer.GridSize/2), float64(layer.GridSize/2))
-
-					opt.GeoM.Translate(float64(tilex), float64(tiley))
-					if i == 1 {
-						opt.ColorScale.ScaleAlpha(0.4)
-					}
+					opt := tileOptions(tileData, layer)
+					opt.ColorScale.ScaleAlpha(alpha)
 					er.Offscreen.DrawImage(tileimg, opt)
 
 					//fmt.Printf("%v+", rect)
@@ -201,11 +208,57 @@ func (er *Renderer) Render(screen *ebiten.Image, level *ldtkgo.Level) {
 	er.camera.Surface.Clear()
 	er.camera.Surface.Fill(color.RGBA{255, 128, 128, 255})
 
-	area := image.Rect(
-		int(er.camera.X), int(er.camera.Y), level.Width, level.Height)
-	areaimg := er.Offscreen.SubImage(area).(*ebiten.Image)
 	opt := &ebiten.DrawImageOptions{}
-	screen.DrawImage(areaimg, opt)
+	opt.GeoM = er.camera.GeoM()
+	screen.DrawImage(er.Offscreen, opt)
+	er.DrawAnimatedTiles(screen)
+}
+
+// Update steps the tiles animation, once per tick
+func (er *Renderer) Update() {
+	er.Tick += 1
+}
+
+/*
+	 ------------------------------------------------------------------------------
+		Draw the platforms entities (plain boxes, there are no platform sprites yet)
+*/
+func (er *Renderer) DrawPlatforms(screen *ebiten.Image, platforms []*sim.Platform) {
+	for _, p := range platforms {
+		if !p.Solid.Active {
+			continue
+		}
+		x, y := er.camera.WorldToScreen(p.Solid.Pos()).XY()
+		w, h := p.Solid.Size().Scale(er.camera.Scale).XY()
+		clr := color.RGBA{0x8b, 0x5a, 0x2b, 0xff}
+		if p.Falls {
+			clr = color.RGBA{0xc0, 0x80, 0x40, 0xff}
+		}
+		vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), float32(h), clr, false)
+	}
+}
+
+/*
+	 ------------------------------------------------------------------------------
+		Draw the enemies (plain boxes colored by AI state, there are no enemy sprites yet)
+*/
+var enemyColors = map[sim.EnemyState]color.RGBA{
+	sim.Enemy_Patrol: {0x40, 0x80, 0xc0, 0xff},
+	sim.Enemy_Chase:  {0xc0, 0x80, 0x20, 0xff},
+	sim.Enemy_Attack: {0xe0, 0x30, 0x30, 0xff},
+	sim.Enemy_Hurt:   {0xff, 0xff, 0xff, 0xff},
+	sim.Enemy_Dead:   {0x60, 0x60, 0x60, 0xff},
+}
+
+func (er *Renderer) DrawEnemies(screen *ebiten.Image, enemies []*sim.Enemy) {
+	for _, e := range enemies {
+		if !e.Active {
+			continue
+		}
+		x, y := er.camera.WorldToScreen(e.Pos()).XY()
+		w, h := e.Size().Scale(er.camera.Scale).XY()
+		vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), float32(h), enemyColors[e.State], false)
+	}
 }
 
 /* ------------------------------------------------------------------------------
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"gogopixel/sim"
+)
+
+// particles alive at most, the new ones are dropped when the pool is full
+const MaxParticles = 1024
+
+// ----------------------------------------------------------------------------- ParticleDef struct
+// ParticleDef is how the particles of an effect are emitted and look. The
+// ranges are [min, max], picked at random for each particle.
+type ParticleDef struct {
+	Rate    float64    // particles per tick of a continuous Emitter
+	Life    [2]int     // ticks
+	VelX    [2]float64 // world pixels per tick
+	VelY    [2]float64
+	Spread  float64 // emission area radius around the position
+	Gravity float64
+	Drag    float64 // velocity lost per tick, 0 to 1
+	Size    [2]float64
+	// colors over the lifetime, evenly spaced and interpolated (alpha included)
+	Colors []color.RGBA
+	// sprite or sub-image, tinted by the colors; nil for a plain square
+	Image *ebiten.Image
+}
+
+// NewParticleDef checks the ranges of the def, min before max
+func NewParticleDef(def ParticleDef) *ParticleDef {
+	if err := def.validate(); err != nil {
+		log.Fatal(err)
+	}
+	return &def
+}
+
+func (def *ParticleDef) validate() error {
+	if def.Life[0] < 1 || def.Life[1] < def.Life[0] {
+		return fmt.Errorf("particles: invalid life %v", def.Life)
+	}
+	for name, r := range map[string][2]float64{"velx": def.VelX, "vely": def.VelY, "size": def.Size} {
+		if r[1] < r[0] {
+			return fmt.Errorf("particles: invalid %s %v", name, r)
+		}
+	}
+	if def.Size[0] < 0 || def.Rate < 0 || def.Drag < 0 || def.Drag > 1 {
+		return fmt.Errorf("particles: invalid size %v, rate %g or drag %g", def.Size, def.Rate, def.Drag)
+	}
+	return nil
+}
+
+// ----------------------------------------------------------------------------- Particle struct
+type Particle struct {
+	Pos, Vel sim.Vec2D[float64]
+	Size     float64
+	Age      int
+	Life     int
+	def      *ParticleDef
+}
+
+// color over the lifetime
+func (p *Particle) color() color.RGBA {
+	colors := p.def.Colors
+	if len(colors) == 0 {
+		return color.RGBA{0xff, 0xff, 0xff, 0xff}
+	}
+	if len(colors) == 1 {
+		return colors[0]
+	}
+	t := float64(p.Age) / float64(p.Life) * float64(len(colors)-1)
+	i := min(int(t), len(colors)-2)
+	f := t - float64(i)
+	a, b := colors[i], colors[i+1]
+	lerp := func(x, y uint8) uint8 { return uint8(float64(x) + (float64(y)-float64(x))*f) }
+	return color.RGBA{lerp(a.R, b.R), lerp(a.G, b.G), lerp(a.B, b.B), lerp(a.A, b.A)}
+}
+
+// ----------------------------------------------------------------------------- Particles struct
+// Particles is the pool of the alive particles, updated each tick and drawn
+// in world space through the camera
+type Particles struct {
+	pool  []Particle // alive ones first
+	alive int
+	rng   *rand.Rand
+	pixel *ebiten.Image
+}
+
+func NewParticles() *Particles {
+	pixel := ebiten.NewImage(1, 1)
+	pixel.Fill(color.White)
+	return &Particles{
+		pool:  make([]Particle, MaxParticles),
+		rng:   rand.New(rand.NewSource(1)),
+		pixel: pixel,
+	}
+}
+
+func (ps *Particles) between(r [2]float64) float64 {
+	return r[0] + ps.rng.Float64()*(r[1]-r[0])
+}
+
+// Burst emits n particles at the world position; the def comes from NewParticleDef
+func (ps *Particles) Burst(def *ParticleDef, pos sim.Vec2D[float64], n int) {
+	for i := 0; i < n && ps.alive < len(ps.pool); i++ {
+		offset := sim.NewVec2D(ps.between([2]float64{-1, 1}), ps.between([2]float64{-1, 1})).Scale(def.Spread)
+		ps.pool[ps.alive] = Particle{
+			Pos:  pos.Add(offset),
+			Vel:  sim.NewVec2D(ps.between(def.VelX), ps.between(def.VelY)),
+			Size: ps.between(def.Size),
+			Life: def.Life[0] + ps.rng.Intn(def.Life[1]-def.Life[0]+1),
+			def:  def,
+		}
+		ps.alive += 1
+	}
+}
+
+// Update moves and ages the particles, the dead ones are swapped out
+func (ps *Particles) Update() {
+	for i := 0; i < ps.alive; {
+		p := &ps.pool[i]
+		p.Age += 1
+		if p.Age >= p.Life {
+			ps.alive -= 1
+			ps.pool[i] = ps.pool[ps.alive]
+			continue
+		}
+		p.Vel = p.Vel.Scale(1 - p.def.Drag)
+		p.Vel.Y += p.def.Gravity
+		p.Pos = p.Pos.Add(p.Vel)
+		i += 1
+	}
+}
+
+// Len returns the number of alive particles
+func (ps *Particles) Len() int {
+	return ps.alive
+}
+
+func (ps *Particles) Clear() {
+	ps.alive = 0
+}
+
+// Draw draws the particles through the camera
+func (ps *Particles) Draw(screen *ebiten.Image, cam *Camera) {
+	geom := cam.GeoM()
+	for i := 0; i < ps.alive; i++ {
+		p := &ps.pool[i]
+		img := p.def.Image
+		if img == nil {
+			img = ps.pixel
+		}
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		opt := &ebiten.DrawImageOptions{}
+		opt.GeoM.Translate(-float64(w)/2, -float64(h)/2)
+		opt.GeoM.Scale(p.Size/float64(w), p.Size/float64(h))
+		opt.GeoM.Translate(p.Pos.XY())
+		opt.GeoM.Concat(geom)
+		c := p.color()
+		opt.ColorScale.Scale(float32(c.R)/0xff, float32(c.G)/0xff, float32(c.B)/0xff, 1)
+		opt.ColorScale.ScaleAlpha(float32(c.A) / 0xff)
+		screen.DrawImage(img, opt)
+	}
+}
+
+// ----------------------------------------------------------------------------- Emitter struct
+// Emitter emits particles continuously at Def.Rate while it is active
+type Emitter struct {
+	Def    *ParticleDef
+	Pos    sim.Vec2D[float64]
+	Active bool
+	acc    float64 // fraction of particle owed
+}
+
+func (e *Emitter) Update(ps *Particles) {
+	if !e.Active {
+		e.acc = 0
+		return
+	}
+	e.acc += e.Def.Rate
+	n := int(e.acc)
+	e.acc -= float64(n)
+	ps.Burst(e.Def, e.Pos, n)
+}
+
+// ----------------------------------------------------------------------------- effects
+var (
+	dustColors = []color.RGBA{{0xe0, 0xd8, 0xc8, 0xc0}, {0xc0, 0xb8, 0xa8, 0x00}}
+	// kicked up behind the player running
+	RunDust = NewParticleDef(ParticleDef{
+		Rate: 0.3, Life: [2]int{12, 20}, VelY: [2]float64{-0.4, -0.1}, Spread: 2,
+		Drag: 0.05, Size: [2]float64{1.5, 3}, Colors: dustColors,
+	})
+	JumpDust = NewParticleDef(ParticleDef{
+		Life: [2]int{10, 18}, VelX: [2]float64{-0.8, 0.8}, VelY: [2]float64{-0.3, 0},
+		Spread: 3, Drag: 0.08, Size: [2]float64{2, 3}, Colors: dustColors,
+	})
+	LandPuff = NewParticleDef(ParticleDef{
+		Life: [2]int{14, 24}, VelX: [2]float64{-1.2, 1.2}, VelY: [2]float64{-0.5, -0.1},
+		Spread: 2, Drag: 0.1, Size: [2]float64{2, 4}, Colors: dustColors,
+	})
+	WallDust = NewParticleDef(ParticleDef{
+		Rate: 0.4, Life: [2]int{10, 16}, VelY: [2]float64{-0.3, 0}, Spread: 2,
+		Drag: 0.05, Size: [2]float64{1, 2.5}, Colors: dustColors,
+	})
+	// the sparkles colors are set by the fruit
+	Sparkles = NewParticleDef(ParticleDef{
+		Life: [2]int{18, 30}, VelX: [2]float64{-1, 1}, VelY: [2]float64{-1.6, -0.4},
+		Spread: 2, Gravity: 0.06, Size: [2]float64{1, 2},
+	})
+)
+
+// particles of a landing puff, doubled at the fall speed of the biggest one
+const (
+	LandPuffCount    = 4
+	LandPuffMaxSpeed = 8.0
+)
+
+// PlayerEffects emits the particles of the player and the pickups
+type PlayerEffects struct {
+	particles *Particles
+	run       Emitter
+	wall      Emitter
+	sparkles  map[string]*ParticleDef // by fruit kind
+}
+
+func NewPlayerEffects(ps *Particles) *PlayerEffects {
+	return &PlayerEffects{
+		particles: ps,
+		run:       Emitter{Def: RunDust},
+		wall:      Emitter{Def: WallDust},
+		sparkles:  map[string]*ParticleDef{},
+	}
+}
+
+// feet returns the world position at the bottom center of the player hitbox
+func feet(p *sim.Player) sim.Vec2D[float64] {
+	box := p.Bounds()
+	return sim.NewVec2D(box.Center().X, box.Bottom())
+}
+
+// Subscribe bursts particles on the player and pickups events
+func (fx *PlayerEffects) Subscribe(events *sim.EventBus, world func() *sim.World) {
+	sim.Subscribe(events, func(sim.PlayerJumped) {
+		fx.particles.Burst(JumpDust, feet(world().Player), 5)
+	})
+	sim.Subscribe(events, func(e sim.PlayerLanded) {
+		if e.Speed < LandSoundSpeed {
+			return
+		}
+		n := LandPuffCount + int(float64(LandPuffCount)*min(e.Speed/LandPuffMaxSpeed, 1))
+		fx.particles.Burst(LandPuff, feet(world().Player), n)
+	})
+	sim.Subscribe(events, func(e sim.ItemCollected) {
+		fx.particles.Burst(fx.sparklesOf(e.Pickup.Kind), e.Pickup.Center(), 10)
+	})
+	sim.Subscribe(events, func(sim.LevelEntered) {
+		fx.particles.Clear()
+	})
+}
+
+func (fx *PlayerEffects) sparklesOf(kind string) *ParticleDef {
+	def, ok := fx.sparkles[kind]
+	if !ok {
+		clr := fruitColor(kind)
+		faded := clr
+		faded.A = 0
+		d := *Sparkles
+		d.Colors = []color.RGBA{{0xff, 0xff, 0xff, 0xff}, clr, faded}
+		def = NewParticleDef(d)
+		fx.sparkles[kind] = def
+	}
+	return def
+}
+
+// Update runs the continuous emitters from the player state
+func (fx *PlayerEffects) Update(p *sim.Player) {
+	fx.run.Active = p.State == sim.Player_Run && p.Grounded
+	fx.run.Pos = feet(p)
+	fx.run.Update(fx.particles)
+
+	fx.wall.Active = p.WallSliding
+	box := p.Bounds()
+	x := box.X
+	if p.Dir == sim.Dir_Right {
+		x = box.Right()
+	}
+	fx.wall.Pos = sim.NewVec2D(x, box.Center().Y)
+	fx.wall.Update(fx.particles)
+}
@@ -1,165 +1,158 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image/color"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 
+	"gogopixel/sim"
+
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/yohamta/ganim8/v2"
 )
 
-type Dir int
-
-const (
-	Dir_Right Dir = iota
-	Dir_Left
-)
-
-// ------------------------------------------------
-type PlayerState int
-
-const (
-	Player_Idle PlayerState = iota
-	Player_Run
-	Player_Jump
-	Player_Climb
-)
-
-// String - Creating common behavior - give the type a String function
-func (d PlayerState) String() string {
-	return [...]string{"Idle", "Run", "Jump", "Climb"}[d]
-}
-
-// EnumIndex - Creating common behavior - give the type a EnumIndex functio
-func (d PlayerState) EnumIndex() int {
-	return int(d)
-}
-
 // ----------------------------------------------
 const (
-	MoveDx = 1.5
 	FrameW = 32
 	FrameH = 32
 )
 
+// Player draws the simulated player (sim.Player) with its animations
 type Player struct {
-	x, y      float64
-	velocity  Vec2D[float64]
-	dir       Dir
-	state     PlayerState
-	grounded  bool
-	images    map[PlayerState]*ebiten.Image
-	anims     map[PlayerState]*ganim8.Animation
+	body      *sim.Player
+	images    map[sim.PlayerState]*ebiten.Image
+	anims     map[sim.PlayerState]*ganim8.Animation
 	curr_anim *ganim8.Animation
 }
 
-func NewPlayer() *Player {
-	p := &Player{}
+// character.json in the character folder
+type CharacterManifest struct {
+	Name    string          `json:"name"`
+	Physics json.RawMessage `json:"physics"` // sim.PlayerPhysics fields
+}
+
+// LoadPhysics returns the player physics: the defaults, overridden by the
+// character manifest (if any) in dir, overridden by the config ones
+func LoadPhysics(dir string, override json.RawMessage) (sim.PlayerPhysics, error) {
+	physics := sim.DefaultPlayerPhysics()
+
+	b, err := os.ReadFile(filepath.Join(dir, "character.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return physics, err
+	}
+	if err == nil {
+		manifest := CharacterManifest{}
+		if err := json.Unmarshal(b, &manifest); err != nil {
+			return physics, fmt.Errorf("%s: %w", filepath.Join(dir, "character.json"), err)
+		}
+		if len(manifest.Physics) > 0 {
+			if err := json.Unmarshal(manifest.Physics, &physics); err != nil {
+				return physics, err
+			}
+		}
+	}
+	if len(override) > 0 {
+		if err := json.Unmarshal(override, &physics); err != nil {
+			return physics, fmt.Errorf("config physics: %w", err)
+		}
+	}
+	return physics, nil
+}
+
+// NewPlayer loads the animations from the character folder (e.g. "assets/hero/Pink Man")
+func NewPlayer(body *sim.Player, dir string) *Player {
+	p := &Player{body: body}
 	// init "map"s for images and animations
-	p.images = map[PlayerState]*ebiten.Image{}
-	p.anims = map[PlayerState]*ganim8.Animation{}
+	p.images = map[sim.PlayerState]*ebiten.Image{}
+	p.anims = map[sim.PlayerState]*ganim8.Animation{}
 
 	var err error
-	p.images[Player_Idle], _, err = ebitenutil.NewImageFromFile("assets/hero/Pink Man/Idle (32x32).png")
+	p.images[sim.Player_Idle], _, err = ebitenutil.NewImageFromFile(filepath.Join(dir, "Idle (32x32).png"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	p.images[sim.Player_Run], _, err = ebitenutil.NewImageFromFile(filepath.Join(dir, "Run (32x32).png"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	p.images[Player_Run], _, err = ebitenutil.NewImageFromFile("assets/hero/Pink Man/Run (32x32).png")
+	p.images[sim.Player_Jump], _, err = ebitenutil.NewImageFromFile(filepath.Join(dir, "Jump (32x32).png"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	p.images[Player_Jump], _, err = ebitenutil.NewImageFromFile("assets/hero/Pink Man/Jump (32x32).png")
+	p.images[sim.Player_Climb], _, err = ebitenutil.NewImageFromFile(filepath.Join(dir, "Wall Jump (32x32).png"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	p.images[Player_Climb], _, err = ebitenutil.NewImageFromFile("assets/hero/Pink Man/Wall Jump (32x32).png")
+	p.images[sim.Player_Hit], _, err = ebitenutil.NewImageFromFile(filepath.Join(dir, "Hit (32x32).png"))
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	idleGrid := ganim8.NewGrid(FrameW, FrameH, 352, 32, 0, 0, 0)
 	// frames referencing >>> (column, grid)
-	p.anims[Player_Idle] = ganim8.New(p.images[Player_Idle], idleGrid.Frames("1-11", 1), time.Millisecond*60)
+	p.anims[sim.Player_Idle] = ganim8.New(p.images[sim.Player_Idle], idleGrid.Frames("1-11", 1), time.Millisecond*60)
 
 	runGrid := ganim8.NewGrid(FrameW, FrameH, 384, 32, 0, 0, 0)
-	p.anims[Player_Run] = ganim8.New(p.images[Player_Run], runGrid.Frames("1-12", 1), time.Millisecond*60)
+	p.anims[sim.Player_Run] = ganim8.New(p.images[sim.Player_Run], runGrid.Frames("1-12", 1), time.Millisecond*60)
 
 	jumpGrid := ganim8.NewGrid(FrameW, FrameH, 32, 32, 0, 0, 0)
-	p.anims[Player_Jump] = ganim8.New(p.images[Player_Jump], jumpGrid.Frames(1, 1), time.Millisecond*60)
+	p.anims[sim.Player_Jump] = ganim8.New(p.images[sim.Player_Jump], jumpGrid.Frames(1, 1), time.Millisecond*60)
 
 	climbGrid := ganim8.NewGrid(FrameW, FrameH, 160, 32, 0, 0, 0)
-	p.anims[Player_Climb] = ganim8.New(p.images[Player_Climb], climbGrid.Frames("1-5", 1), time.Millisecond*60)
+	p.anims[sim.Player_Climb] = ganim8.New(p.images[sim.Player_Climb], climbGrid.Frames("1-5", 1), time.Millisecond*60)
 
-	p.state = Player_Idle
-	p.grounded = true
-	p.curr_anim = p.anims[p.state]
+	hitGrid := ganim8.NewGrid(FrameW, FrameH, 224, 32, 0, 0, 0)
+	p.anims[sim.Player_Hit] = ganim8.New(p.images[sim.Player_Hit], hitGrid.Frames("1-7", 1), time.Millisecond*60)
+	// dying plays the hit animation once, slower
+	p.anims[sim.Player_Dead] = ganim8.New(p.images[sim.Player_Hit], hitGrid.Frames("1-7", 1), time.Millisecond*100, ganim8.PauseAtEnd)
 
-	p.x = 50.0
-	p.y = 150.0
-	p.velocity = Vec2D[float64]{0., 0.}
-	p.dir = Dir_Right
+	p.curr_anim = p.anims[p.body.State]
 
 	return p
 }
 
-func (p *Player) Move(dx, dy float64) {
-	// Get the delta time
-	//dt := 1 / ebiten.ActualTPS()
-	if dx > 0 {
-		p.dir = Dir_Right
-	} else if dx < 0 {
-		p.dir = Dir_Left
-	}
-	p.velocity.X = (dx * MoveDx)
-	p.velocity.Y = (dy * MoveDx)
-}
-
-func (p *Player) CycleAnim() {
-	if p.state < Player_Climb {
-		p.state += 1
-		fmt.Println("cycle anim")
-	} else {
-		p.state = Player_Idle
-	}
-}
-
+// Update only advances the animation, the player state is updated by the simulation
 func (p *Player) Update() error {
-
-	// speed := rotationPerSecond / float64(ebiten.TPS())
-	p.x += p.velocity.X
-	p.y += p.velocity.Y
-
-	if p.velocity.X > 0 {
-		p.velocity.X -= MoveDx
-	} else if p.velocity.X < 0 {
-		p.velocity.X += MoveDx
-	}
-
-	if p.velocity.Y > 0 {
-		p.velocity.Y -= MoveDx
+	anim := p.anims[p.body.State]
+	if anim != p.curr_anim && p.body.State == sim.Player_Dead {
+		// the death animation plays from the start every time
+		anim.PauseAtStart()
+		anim.Resume()
 	}
-
-	p.curr_anim = p.anims[p.state]
+	p.curr_anim = anim
 	p.curr_anim.Update()
 
 	return nil
 }
 
-func (p *Player) Draw(screen *ebiten.Image) {
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(p.x, p.y)
+func (p *Player) Draw(screen *ebiten.Image, cam *Camera) {
+	// blink while invulnerable
+	if p.body.Invulnerable > 0 && (p.body.Invulnerable/4)%2 == 1 {
+		return
+	}
 	// // The paramters are x, y, rotate (in radian), scaleX, scaleY
 	// originX, originY.
-	sx := 1.0
-	px := p.x
-	if p.dir == Dir_Left {
-		sx = -1.
-		px += FrameW // (64 / 2)
-	} else if p.dir == Dir_Right {
-		sx = 1.
+	sx := cam.Scale
+	px, py := cam.WorldToScreen(p.body.Pos).XY()
+	if p.body.Dir == sim.Dir_Left {
+		sx = -cam.Scale
+		px += FrameW * cam.Scale // (64 / 2)
 	}
 	//opt.GeoM.Translate(float64(-layer.GridSize/2), float64(-layer.GridSize/2))
-	p.curr_anim.Draw(screen, ganim8.DrawOpts(px, p.y, 0, sx, 1.0))
+	p.curr_anim.Draw(screen, ganim8.DrawOpts(px, py, cam.Rot, sx, cam.Scale))
+}
+
+// DrawHitbox outlines the player hitbox (debug)
+func (p *Player) DrawHitbox(screen *ebiten.Image, cam *Camera) {
+	box := p.body.Bounds()
+	x, y := cam.WorldToScreen(box.Pos()).XY()
+	w, h := box.Size().Scale(cam.Scale).XY()
+	vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 1, color.RGBA{0, 255, 0, 255}, false)
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sync/atomic"
+
+	"gogopixel/sim"
+)
+
+// ----------------------------------------------------------------------------- Falloff
+// Falloff is how the volume of a positional sound decreases with its distance
+type Falloff int
+
+const (
+	Falloff_Linear Falloff = iota
+	Falloff_Quadratic
+	Falloff_Inverse
+)
+
+// rolloff of the inverse falloff: the higher, the faster the volume drops near the listener
+const InverseRolloff = 4.0
+
+func (f Falloff) String() string {
+	return [...]string{"Linear", "Quadratic", "Inverse"}[f]
+}
+
+// Gain returns the volume at the distance ratio r, from 1 at the min distance
+// to 0 at the max distance
+func (f Falloff) Gain(r float64) float64 {
+	r = math.Min(math.Max(r, 0), 1)
+	switch f {
+	case Falloff_Quadratic:
+		return (1 - r) * (1 - r)
+	case Falloff_Inverse:
+		// 1/(1+k*r), scaled to reach 0 at r = 1
+		end := 1 / (1 + InverseRolloff)
+		return (1/(1+InverseRolloff*r) - end) / (1 - end)
+	}
+	return 1 - r
+}
+
+// ----------------------------------------------------------------------------- SoundSpace struct
+// SoundSpace sets how the sounds are heard from the camera center, the
+// distances are in screen pixels
+type SoundSpace struct {
+	MinDistance float64 // full volume below
+	MaxDistance float64 // not heard from, the sounds are culled
+	Falloff     Falloff
+	PanWidth    float64 // pan of a sound at the screen edge, 0 (mono) to 1
+}
+
+// DefaultSoundSpace is the sound space of a view of the width, in screen pixels:
+// the sounds fade out a little past its edges
+func DefaultSoundSpace(viewWidth int) SoundSpace {
+	return SoundSpace{
+		MinDistance: 32,
+		MaxDistance: float64(viewWidth) * 0.75,
+		Falloff:     Falloff_Inverse,
+		PanWidth:    0.8,
+	}
+}
+
+// SetListener sets the camera the positional sounds are heard from, and the
+// default sound space of its view
+func (a *Audio) SetListener(cam *Camera) {
+	a.listener = cam
+	a.Space = DefaultSoundSpace(cam.Width)
+}
+
+// spatial returns the volume and the pan (-1 left to 1 right) of a sound at the
+// world position, false when it is too far to be heard
+func (a *Audio) spatial(pos sim.Vec2D[float64]) (float64, float64, bool) {
+	cam := a.listener
+	if cam == nil {
+		return 1, 0, true
+	}
+	sp := a.Space
+	center := cam.center()
+	d := sim.NewVec2D(cam.WorldToScreenCoords(pos.XY())).Sub(center)
+	dist := d.Length()
+	if dist >= sp.MaxDistance {
+		return 0, 0, false
+	}
+	gain := 1.
+	if dist > sp.MinDistance {
+		gain = sp.Falloff.Gain((dist - sp.MinDistance) / (sp.MaxDistance - sp.MinDistance))
+	}
+	pan := math.Min(math.Max(d.X/center.X, -1), 1) * sp.PanWidth
+	return gain, pan, true
+}
+
+// PlayAt starts the sound effect at the world position, panned and attenuated
+// from the camera center; it is not played when too far to be heard
+func (a *Audio) PlayAt(name string, pos sim.Vec2D[float64]) {
+	s, ok := a.sounds[name]
+	if !ok {
+		return
+	}
+	gain, pan, audible := a.spatial(pos)
+	if !audible {
+		return
+	}
+	v := s.voice(a.ctx)
+	v.positional = true
+	v.pos = pos
+	v.stream.SetPan(pan)
+	v.player.SetVolume(a.Volumes.Master * a.Volumes.SFX * gain)
+	v.player.Rewind()
+	v.player.Play()
+}
+
+// updateVoices pans and attenuates the playing positional sounds as the camera
+// moves, muting the ones out of reach
+func (a *Audio) updateVoices() {
+	for _, s := range a.sounds {
+		for _, v := range s.voices {
+			if !v.positional || !v.player.IsPlaying() {
+				continue
+			}
+			gain, pan, _ := a.spatial(v.pos)
+			v.stream.SetPan(pan)
+			v.player.SetVolume(a.Volumes.Master * a.Volumes.SFX * gain)
+		}
+	}
+}
+
+// ----------------------------------------------------------------------------- panStream struct
+// panStream pans 16-bit stereo samples (the decoded format); the pan is set by
+// the game and read by the audio goroutine
+type panStream struct {
+	*bytes.Reader
+	pan atomic.Uint64 // float64 bits
+}
+
+func newPanStream(data []byte) *panStream {
+	return &panStream{Reader: bytes.NewReader(data)}
+}
+
+func (s *panStream) SetPan(pan float64) {
+	s.pan.Store(math.Float64bits(pan))
+}
+
+func (s *panStream) Read(b []byte) (int, error) {
+	// whole frames: 2 channels of 2 bytes
+	n, err := s.Reader.Read(b[:len(b)/4*4])
+	pan := math.Float64frombits(s.pan.Load())
+	if pan == 0 {
+		return n, err
+	}
+	left, right := math.Min(1, 1-pan), math.Min(1, 1+pan)
+	for i := 0; i+4 <= n; i += 4 {
+		l := float64(int16(binary.LittleEndian.Uint16(b[i:])))
+		r := float64(int16(binary.LittleEndian.Uint16(b[i+2:])))
+		binary.LittleEndian.PutUint16(b[i:], uint16(int16(l*left)))
+		binary.LittleEndian.PutUint16(b[i+2:], uint16(int16(r*right)))
+	}
+	return n, err
+}
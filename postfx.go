@@ -0,0 +1,294 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// the post-processing shaders, shaders/<name>.kage
+//
+//go:embed shaders/*.kage
+var shaderFiles embed.FS
+
+// the uniforms of the shaders, with their default values: a float is a float32,
+// a vector or an array a []float32 of its size
+var shaderDefaults = map[string]map[string]any{
+	"crt": {
+		"Scanline":  float32(0.25),
+		"Curvature": float32(0.04),
+	},
+	"palette": {
+		// the 4 greens of the Game Boy
+		"Palette": []float32{
+			0.06, 0.22, 0.06, 1,
+			0.19, 0.38, 0.19, 1,
+			0.55, 0.67, 0.06, 1,
+			0.61, 0.74, 0.06, 1,
+		},
+		"Amount": float32(1),
+	},
+	"vignette": {
+		"Intensity": float32(0.6),
+		"Radius":    float32(0.5),
+	},
+	"chromatic": {
+		"Offset": float32(1),
+	},
+	"grade": {
+		"Brightness": float32(0),
+		"Contrast":   float32(1.1),
+		"Saturation": float32(1.1),
+		"Tint":       []float32{1, 1, 1},
+	},
+}
+
+// ShaderNames returns the names of the post-processing shaders, sorted
+func ShaderNames() []string {
+	names := make([]string, 0, len(shaderDefaults))
+	for name := range shaderDefaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shaderParam converts a config value (a JSON number or array of numbers) to
+// the type of the shader uniform
+func shaderParam(shader, name string, value any) (any, error) {
+	def, ok := shaderDefaults[shader][name]
+	if !ok {
+		return nil, fmt.Errorf("postfx %s: unknown param %q", shader, name)
+	}
+	switch def := def.(type) {
+	case float32:
+		switch v := value.(type) {
+		case float64:
+			return float32(v), nil
+		case float32:
+			return v, nil
+		case int:
+			return float32(v), nil
+		}
+	case []float32:
+		var values []float32
+		switch v := value.(type) {
+		case []float32:
+			values = v
+		case []float64:
+			for _, f := range v {
+				values = append(values, float32(f))
+			}
+		case []any:
+			for _, e := range v {
+				f, ok := e.(float64)
+				if !ok {
+					return nil, fmt.Errorf("postfx %s: param %s: %v is not a number", shader, name, e)
+				}
+				values = append(values, float32(f))
+			}
+		}
+		if values != nil && len(values) == len(def) {
+			return values, nil
+		}
+		return nil, fmt.Errorf("postfx %s: param %s needs %d numbers", shader, name, len(def))
+	}
+	return nil, fmt.Errorf("postfx %s: param %s: invalid value %v", shader, name, value)
+}
+
+// PostPass is a pass of the post-processing chain in the config
+type PostPass struct {
+	Shader   string         `json:"shader"`
+	Params   map[string]any `json:"params"` // uniforms overriding the defaults
+	Disabled bool           `json:"disabled"`
+}
+
+func (p PostPass) validate() error {
+	if _, ok := shaderDefaults[p.Shader]; !ok {
+		return fmt.Errorf("unknown postfx shader %q (%s)", p.Shader, strings.Join(ShaderNames(), ", "))
+	}
+	for name, value := range p.Params {
+		if _, err := shaderParam(p.Shader, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParsePostPasses parses a comma separated list of shaders, e.g. "crt,vignette";
+// the passes already in the chain keep their params
+func ParsePostPasses(s string, chain []PostPass) ([]PostPass, error) {
+	passes := []PostPass{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		pass := PostPass{Shader: name}
+		for _, p := range chain {
+			if p.Shader == name {
+				pass = p
+				pass.Disabled = false
+				break
+			}
+		}
+		if err := pass.validate(); err != nil {
+			return nil, err
+		}
+		passes = append(passes, pass)
+	}
+	return passes, nil
+}
+
+// ----------------------------------------------------------------------------- PostFX struct
+// Pass is a shader pass of the post-processing chain
+type Pass struct {
+	Name     string // the shader
+	Enabled  bool
+	Uniforms map[string]any
+	shader   *ebiten.Shader
+}
+
+// PostFX passes the frame through its ordered shader passes; the frame is drawn
+// as is when there is no enabled pass
+type PostFX struct {
+	Passes  []*Pass
+	Enabled bool // the whole chain, toggled with F3
+	shaders map[string]*ebiten.Shader
+	buffers [2]*ebiten.Image
+}
+
+func NewPostFX(chain []PostPass) *PostFX {
+	fx := &PostFX{
+		Enabled: true,
+		shaders: map[string]*ebiten.Shader{},
+	}
+	for _, p := range chain {
+		pass, err := fx.Add(p.Shader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for name, value := range p.Params {
+			if err := pass.SetParam(name, value); err != nil {
+				log.Fatal(err)
+			}
+		}
+		pass.Enabled = !p.Disabled
+	}
+	return fx
+}
+
+// shader compiles the shader the first time it is used
+func (fx *PostFX) shader(name string) (*ebiten.Shader, error) {
+	if s, ok := fx.shaders[name]; ok {
+		return s, nil
+	}
+	if _, ok := shaderDefaults[name]; !ok {
+		return nil, fmt.Errorf("unknown postfx shader %q", name)
+	}
+	src, err := shaderFiles.ReadFile("shaders/" + name + ".kage")
+	if err != nil {
+		return nil, err
+	}
+	s, err := ebiten.NewShader(src)
+	if err != nil {
+		return nil, fmt.Errorf("shader %s: %w", name, err)
+	}
+	fx.shaders[name] = s
+	return s, nil
+}
+
+// Add appends an enabled pass of the shader, with its default params, to the chain
+func (fx *PostFX) Add(name string) (*Pass, error) {
+	s, err := fx.shader(name)
+	if err != nil {
+		return nil, err
+	}
+	pass := &Pass{Name: name, Enabled: true, Uniforms: map[string]any{}, shader: s}
+	for k, v := range shaderDefaults[name] {
+		pass.Uniforms[k] = v
+	}
+	fx.Passes = append(fx.Passes, pass)
+	return pass, nil
+}
+
+// Remove removes the passes of the shader from the chain
+func (fx *PostFX) Remove(name string) {
+	passes := fx.Passes[:0]
+	for _, p := range fx.Passes {
+		if p.Name != name {
+			passes = append(passes, p)
+		}
+	}
+	clear(fx.Passes[len(passes):])
+	fx.Passes = passes
+}
+
+// Pass returns the first pass of the shader, nil if it is not in the chain
+func (fx *PostFX) Pass(name string) *Pass {
+	for _, p := range fx.Passes {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// SetParam sets a uniform of the pass, a number or a slice of numbers
+func (p *Pass) SetParam(name string, value any) error {
+	v, err := shaderParam(p.Name, name, value)
+	if err != nil {
+		return err
+	}
+	p.Uniforms[name] = v
+	return nil
+}
+
+// Active reports whether the chain changes the frame
+func (fx *PostFX) Active() bool {
+	if !fx.Enabled {
+		return false
+	}
+	for _, p := range fx.Passes {
+		if p.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply runs the enabled passes on the frame, ping-ponging between two
+// offscreen buffers; it returns the frame itself when the chain is bypassed
+func (fx *PostFX) Apply(frame *ebiten.Image) *ebiten.Image {
+	if !fx.Active() {
+		return frame
+	}
+	size := frame.Bounds().Size()
+	for i, b := range fx.buffers {
+		if b == nil || b.Bounds().Size() != size {
+			if b != nil {
+				b.Dispose()
+			}
+			fx.buffers[i] = ebiten.NewImage(size.X, size.Y)
+		}
+	}
+	src := frame
+	n := 0
+	for _, p := range fx.Passes {
+		if !p.Enabled {
+			continue
+		}
+		dst := fx.buffers[n%2]
+		op := &ebiten.DrawRectShaderOptions{Blend: ebiten.BlendCopy}
+		op.Images[0] = src
+		op.Uniforms = p.Uniforms
+		dst.DrawRectShader(size.X, size.Y, p.shader, op)
+		src = dst
+		n += 1
+	}
+	return src
+}
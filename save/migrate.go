@@ -0,0 +1,91 @@
+package save
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// raw is a save file decoded only at the top level, so migrations can rename,
+// add or drop fields without knowing the old struct layouts.
+type raw map[string]json.RawMessage
+
+// migrations[v] upgrades a version v save to version v+1.
+// When the format changes: bump CurrentVersion and add the migration from the previous one.
+var migrations = map[int]func(raw) error{
+	1: migrateV1,
+}
+
+// Decode parses a save file of any known version and migrates it to CurrentVersion.
+func Decode(b []byte) (*Data, error) {
+	r := raw{}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+
+	v, ok := r["version"]
+	if !ok {
+		return nil, fmt.Errorf("save: no version")
+	}
+	version := 0
+	if err := json.Unmarshal(v, &version); err != nil {
+		return nil, err
+	}
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("save: version %d is newer than supported version %d", version, CurrentVersion)
+	}
+
+	for ; version < CurrentVersion; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("save: no migration from version %d", version)
+		}
+		if err := migrate(r); err != nil {
+			return nil, fmt.Errorf("save: migrating version %d: %w", version, err)
+		}
+	}
+	r["version"], _ = json.Marshal(CurrentVersion)
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	d := &Data{}
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// migrateV1 qualifies the item ids of the version 1 saves by the level
+// identifier, the ids were those of the saved level; the score starts at 0.
+func migrateV1(r raw) error {
+	var level string
+	if v, ok := r["level"]; ok {
+		if err := json.Unmarshal(v, &level); err != nil {
+			return err
+		}
+	}
+	for _, field := range []string{"collected", "checkpoints"} {
+		var ids []string
+		if v, ok := r[field]; ok {
+			if err := json.Unmarshal(v, &ids); err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+		}
+		for i, id := range ids {
+			ids[i] = level + "/" + id
+		}
+		if ids == nil {
+			ids = []string{}
+		}
+		b, err := json.Marshal(ids)
+		if err != nil {
+			return err
+		}
+		r[field] = b
+	}
+	if _, ok := r["score"]; !ok {
+		r["score"] = json.RawMessage("0")
+	}
+	return nil
+}
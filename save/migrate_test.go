@@ -0,0 +1,56 @@
+package save
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeVersion(t *testing.T) {
+	for _, b := range []string{`{"level": "Level_0"}`, `{"version": 999}`} {
+		if _, err := Decode([]byte(b)); err == nil {
+			t.Errorf("decoded %s", b)
+		}
+	}
+}
+
+func TestDecodeV1(t *testing.T) {
+	b, err := os.ReadFile("testdata/slot_v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Version != CurrentVersion || d.Score != 0 {
+		t.Errorf("version %d score %d", d.Version, d.Score)
+	}
+	if want := []string{"Level_0/apple_1"}; !reflect.DeepEqual(d.Collected, want) {
+		t.Errorf("collected %v, want %v", d.Collected, want)
+	}
+	if want := []string{"Level_0/start"}; !reflect.DeepEqual(d.Checkpoints, want) {
+		t.Errorf("checkpoints %v, want %v", d.Checkpoints, want)
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	s := NewStore(t.TempDir())
+	if _, err := s.Load(1); err != ErrNoSave {
+		t.Fatalf("empty slot: %v, want ErrNoSave", err)
+	}
+	d := &Data{Level: "Level_0", Player: PlayerData{X: 10, Y: 20}, Collected: []string{}, Checkpoints: []string{"cp"}}
+	if err := s.Save(1, d); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Load(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Level != d.Level || got.Player != d.Player || !reflect.DeepEqual(got.Checkpoints, d.Checkpoints) {
+		t.Errorf("loaded %+v, saved %+v", got, d)
+	}
+	if slots := s.Slots(); !reflect.DeepEqual(slots, []int{1}) {
+		t.Errorf("slots %v", slots)
+	}
+}
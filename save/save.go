@@ -0,0 +1,156 @@
+// Package save reads and writes versioned game saves, one JSON file per slot,
+// in the user config directory.
+package save
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gogopixel/sim"
+)
+
+// CurrentVersion is the version written by Save; older files are migrated on Load.
+const CurrentVersion = 2
+
+var ErrNoSave = errors.New("save: slot is empty")
+
+// ----------------------------------------------------------------------------- Data struct
+type PlayerData struct {
+	X      float64         `json:"x"`
+	Y      float64         `json:"y"`
+	State  sim.PlayerState `json:"state"`
+	Facing sim.Dir         `json:"facing"`
+}
+
+type Data struct {
+	Version     int        `json:"version"`
+	SavedAt     time.Time  `json:"saved_at"`
+	Level       string     `json:"level"` // level identifier
+	Player      PlayerData `json:"player"`
+	Collected   []string   `json:"collected"`   // ids of the collected items, "<level>/<id>"
+	Checkpoints []string   `json:"checkpoints"` // ids of the unlocked checkpoints, "<level>/<id>"
+	Score       int        `json:"score"`
+}
+
+// Capture builds the save data of the current simulation state.
+func Capture(w *sim.World) *Data {
+	return &Data{
+		Version: CurrentVersion,
+		Level:   w.Level.Identifier,
+		Player: PlayerData{
+			X:      w.Player.Pos.X,
+			Y:      w.Player.Pos.Y,
+			State:  w.Player.State,
+			Facing: w.Player.Dir,
+		},
+		Collected:   []string{},
+		Checkpoints: []string{},
+	}
+}
+
+// ApplyPlayer restores the saved player state (the level must already be loaded).
+func (d *Data) ApplyPlayer(p *sim.Player) {
+	p.Pos = sim.NewVec2D(d.Player.X, d.Player.Y)
+	p.State = d.Player.State
+	p.Dir = d.Player.Facing
+	p.Velocity = sim.Vec2D[float64]{}
+}
+
+// ----------------------------------------------------------------------------- Store struct
+// Store keeps the save slots in a directory.
+type Store struct {
+	Dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// DefaultStore uses <user config dir>/gogopixel/saves
+func DefaultStore() (*Store, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(filepath.Join(base, "gogopixel", "saves")), nil
+}
+
+func (s *Store) path(slot int) string {
+	return filepath.Join(s.Dir, fmt.Sprintf("slot%d.json", slot))
+}
+
+// Exists reports whether the slot holds a save.
+func (s *Store) Exists(slot int) bool {
+	_, err := os.Stat(s.path(slot))
+	return err == nil
+}
+
+// Save writes the slot atomically: the data goes to a temp file that replaces
+// the old save only once it is completely written.
+func (s *Store) Save(slot int, d *Data) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	d.Version = CurrentVersion
+	d.SavedAt = time.Now()
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, fmt.Sprintf("slot%d-*.tmp", slot))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(slot))
+}
+
+// Load reads the slot, migrating it to CurrentVersion if needed.
+func (s *Store) Load(slot int) (*Data, error) {
+	b, err := os.ReadFile(s.path(slot))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSave
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Decode(b)
+}
+
+// Delete empties the slot.
+func (s *Store) Delete(slot int) error {
+	err := os.Remove(s.path(slot))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Slots returns the numbers of the slots holding a save.
+func (s *Store) Slots() []int {
+	slots := []int{}
+	files, _ := filepath.Glob(filepath.Join(s.Dir, "slot*.json"))
+	for _, f := range files {
+		var n int
+		if _, err := fmt.Sscanf(filepath.Base(f), "slot%d.json", &n); err == nil {
+			slots = append(slots, n)
+		}
+	}
+	return slots
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"gogopixel/save"
+)
+
+// SaveGame writes the current game state in the save slot
+func (g *Game) SaveGame() error {
+	if g.saves == nil {
+		return errors.New("no save store")
+	}
+	if g.config.Debug.Verbose {
+		fmt.Printf("Saving slot %d (%s)\n", g.SaveSlot, g.saves.Dir)
+	}
+	data := save.Capture(g.world)
+	data.Score = g.progress.Score
+	data.Collected = g.progress.Collected
+	data.Checkpoints = g.progress.Checkpoints
+	return g.saves.Save(g.SaveSlot, data)
+}
+
+// LoadGame restores the game state from the save slot
+func (g *Game) LoadGame() error {
+	if g.saves == nil {
+		return errors.New("no save store")
+	}
+	data, err := g.saves.Load(g.SaveSlot)
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i, level := range g.LDTKProject.Levels {
+		if level.Identifier == data.Level {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("saved level %q not found", data.Level)
+	}
+
+	if g.config.Debug.Verbose {
+		fmt.Printf("Loading slot %d (level %s)\n", g.SaveSlot, data.Level)
+	}
+	g.progress = Progress{Score: data.Score, Collected: data.Collected, Checkpoints: data.Checkpoints}
+	g.LoadLevel(index)
+	data.ApplyPlayer(g.world.Player)
+	return nil
+}
@@ -0,0 +1,208 @@
+package sim
+
+import (
+	"math"
+	"strings"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// ------------------------------------------------
+// Cell is the gameplay meaning of a collision grid cell
+type Cell uint8
+
+const (
+	CellEmpty Cell = iota
+	CellSolid
+	CellOneWay // platform: solid only from above, the player can drop through
+	CellHazard // spikes: damage the player
+	CellLadder
+	CellWater
+)
+
+// CellIce is solid ground with little friction (numbered after the slope cells)
+const CellIce = CellSlopeDown22Low + 1
+
+func (c Cell) String() string {
+	return [...]string{"Empty", "Solid", "OneWay", "Hazard", "Ladder", "Water",
+		"SlopeUp45", "SlopeDown45", "SlopeUp22Low", "SlopeUp22High", "SlopeDown22High", "SlopeDown22Low",
+		"Ice"}[c]
+}
+
+// IsSolid reports whether the cell blocks from every side
+func (c Cell) IsSolid() bool {
+	return c == CellSolid || c == CellIce
+}
+
+// CellsByIdentifier maps the IntGrid value identifiers (as named in the LDtk
+// layer definitions, case insensitive) to cells. Unnamed or unknown values are solid.
+var CellsByIdentifier = map[string]Cell{
+	"solid":    CellSolid,
+	"wall":     CellSolid,
+	"ground":   CellSolid,
+	"one_way":  CellOneWay,
+	"oneway":   CellOneWay,
+	"platform": CellOneWay,
+	"hazard":   CellHazard,
+	"spikes":   CellHazard,
+	"ladder":   CellLadder,
+	"water":    CellWater,
+	"ice":      CellIce,
+}
+
+func cellByIdentifier(identifier string) Cell {
+	if c, ok := CellsByIdentifier[strings.ToLower(identifier)]; ok {
+		return c
+	}
+	return CellSolid
+}
+
+// ----------------------------------------------------------------------------- CollisionGrid struct
+// CollisionGrid is the static level geometry as a grid of cells.
+// It is built only from ldtkgo data so it can be used without a display.
+type CollisionGrid struct {
+	Width, Height int // size in cells
+	CellSize      int // size of a cell in pixels
+	cells         []Cell
+}
+
+// NewCollisionGrid builds the grid from the IntGrid layers of the level, the
+// values meaning given by their identifiers in defs. Levels without IntGrid
+// layers collide with every tile instead. Tiles tagged as slopes in their
+// tileset (see SlopesByIdentifier) are slopes in either case.
+func NewCollisionGrid(level *ldtkgo.Level, defs *Defs) *CollisionGrid {
+	cellSize := 16
+	hasIntGrid := false
+	for _, layer := range level.Layers {
+		if layer.Type == ldtkgo.LayerTypeIntGrid {
+			hasIntGrid = true
+		}
+	}
+	for _, layer := range level.Layers {
+		if layer.Type != ldtkgo.LayerTypeEntity && layer.GridSize > 0 {
+			if !hasIntGrid || layer.Type == ldtkgo.LayerTypeIntGrid {
+				cellSize = layer.GridSize
+				break
+			}
+		}
+	}
+
+	g := &CollisionGrid{
+		Width:    int(math.Ceil(float64(level.Width) / float64(cellSize))),
+		Height:   int(math.Ceil(float64(level.Height) / float64(cellSize))),
+		CellSize: cellSize,
+	}
+	g.cells = make([]Cell, g.Width*g.Height)
+
+	for _, layer := range level.Layers {
+		switch layer.Type {
+		case ldtkgo.LayerTypeIntGrid:
+			for _, integer := range layer.IntGrid {
+				cell := cellByIdentifier(defs.IntGridIdentifier(layer.Identifier, integer.Value))
+				g.set(integer.Position[0]+layer.OffsetX, integer.Position[1]+layer.OffsetY, layer.GridSize, cell)
+			}
+		case ldtkgo.LayerTypeAutoTile:
+			fallthrough
+		case ldtkgo.LayerTypeTile:
+			for _, tile := range layer.AllTiles() {
+				if !hasIntGrid {
+					g.set(tile.Position[0]+layer.OffsetX, tile.Position[1]+layer.OffsetY, layer.GridSize, CellSolid)
+				}
+			}
+		}
+	}
+	// slopes last, so they are not overwritten by the IntGrid
+	for _, layer := range level.Layers {
+		if layer.Type == ldtkgo.LayerTypeEntity {
+			continue
+		}
+		for _, tile := range layer.AllTiles() {
+			if slope, ok := tileSlope(NewTileMeta(layer.Tileset, tile.ID), tile); ok {
+				g.set(tile.Position[0]+layer.OffsetX, tile.Position[1]+layer.OffsetY, layer.GridSize, slope)
+			}
+		}
+	}
+	return g
+}
+
+// set the cells covered by a size x size square at pixel position (px, py)
+func (g *CollisionGrid) set(px, py, size int, cell Cell) {
+	for y := py / g.CellSize; y <= (py+size-1)/g.CellSize; y++ {
+		for x := px / g.CellSize; x <= (px+size-1)/g.CellSize; x++ {
+			if g.inside(x, y) {
+				g.cells[y*g.Width+x] = cell
+			}
+		}
+	}
+}
+
+func (g *CollisionGrid) inside(cx, cy int) bool {
+	return cx >= 0 && cy >= 0 && cx < g.Width && cy < g.Height
+}
+
+// Cell returns the cell (cx, cy); cells outside the level are empty.
+func (g *CollisionGrid) Cell(cx, cy int) Cell {
+	if !g.inside(cx, cy) {
+		return CellEmpty
+	}
+	return g.cells[cy*g.Width+cx]
+}
+
+// CellAt returns the cell at the world position (x, y).
+func (g *CollisionGrid) CellAt(x, y float64) Cell {
+	return g.Cell(g.ToCell(x), g.ToCell(y))
+}
+
+// IsSolid reports whether the cell (cx, cy) is solid.
+func (g *CollisionGrid) IsSolid(cx, cy int) bool {
+	return g.Cell(cx, cy).IsSolid()
+}
+
+// IsSolidAt reports whether the world position (x, y) lies in a solid cell.
+func (g *CollisionGrid) IsSolidAt(x, y float64) bool {
+	return g.IsSolid(g.ToCell(x), g.ToCell(y))
+}
+
+// ToCell converts a world coordinate to a cell coordinate.
+func (g *CollisionGrid) ToCell(v float64) int {
+	return int(math.Floor(v / float64(g.CellSize)))
+}
+
+// Overlaps reports whether the box (x, y, w, h) touches any solid cell.
+func (g *CollisionGrid) Overlaps(x, y, w, h float64) bool {
+	for cy := g.ToCell(y); cy <= g.ToCell(y+h-collisionEps); cy++ {
+		for cx := g.ToCell(x); cx <= g.ToCell(x+w-collisionEps); cx++ {
+			if g.IsSolid(cx, cy) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Touches reports whether the box (x, y, w, h) touches any cell of the given kind.
+func (g *CollisionGrid) Touches(x, y, w, h float64, cell Cell) bool {
+	for cy := g.ToCell(y); cy <= g.ToCell(y+h-collisionEps); cy++ {
+		for cx := g.ToCell(x); cx <= g.ToCell(x+w-collisionEps); cx++ {
+			if g.Cell(cx, cy) == cell {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsPlatform reports whether the cell (cx, cy) can be stood on from above without
+// being solid: one-way platforms and the top of ladders.
+func (g *CollisionGrid) IsPlatform(cx, cy int) bool {
+	switch g.Cell(cx, cy) {
+	case CellOneWay:
+		return true
+	case CellLadder:
+		return g.Cell(cx, cy-1) != CellLadder
+	}
+	return false
+}
+
+// keeps boxes exactly aligned to a cell edge from overlapping the next cell
+const collisionEps = 0.001
@@ -0,0 +1,114 @@
+package sim
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// Defs holds the LDtk project definitions (and instance ids) that ldtkgo does not expose.
+type Defs struct {
+	// IntGrid value identifiers: layer identifier -> value -> identifier
+	IntGrid map[string]map[int]string
+	// level iid -> level identifier
+	Levels map[string]string
+	// entity iids: level identifier -> layer index -> entity index -> iid
+	entityIIDs map[string][][]string
+}
+
+// OpenDefs reads the definitions of the LDtk project file.
+func OpenDefs(path string) (*Defs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ReadDefs(data)
+}
+
+// ReadDefs reads the definitions from the LDtk project JSON.
+func ReadDefs(data []byte) (*Defs, error) {
+	var project struct {
+		Defs struct {
+			Layers []struct {
+				Identifier    string `json:"identifier"`
+				Type          string `json:"type"`
+				IntGridValues []struct {
+					Value      int    `json:"value"`
+					Identifier string `json:"identifier"`
+				} `json:"intGridValues"`
+			} `json:"layers"`
+		} `json:"defs"`
+		Levels []struct {
+			Identifier     string `json:"identifier"`
+			IID            string `json:"iid"`
+			LayerInstances []struct {
+				EntityInstances []struct {
+					IID string `json:"iid"`
+				} `json:"entityInstances"`
+			} `json:"layerInstances"`
+		} `json:"levels"`
+	}
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, err
+	}
+
+	defs := &Defs{
+		IntGrid:    map[string]map[int]string{},
+		Levels:     map[string]string{},
+		entityIIDs: map[string][][]string{},
+	}
+	for _, layer := range project.Defs.Layers {
+		if len(layer.IntGridValues) == 0 {
+			continue
+		}
+		values := map[int]string{}
+		for _, v := range layer.IntGridValues {
+			values[v.Value] = v.Identifier
+		}
+		defs.IntGrid[layer.Identifier] = values
+	}
+	// ldtkgo keeps the layers and entities in the file order
+	for _, level := range project.Levels {
+		defs.Levels[level.IID] = level.Identifier
+		layers := [][]string{}
+		for _, layer := range level.LayerInstances {
+			iids := []string{}
+			for _, e := range layer.EntityInstances {
+				iids = append(iids, e.IID)
+			}
+			layers = append(layers, iids)
+		}
+		defs.entityIIDs[level.Identifier] = layers
+	}
+	return defs, nil
+}
+
+// EntityIID returns the iid of the entity at the index of the layer at layerIndex
+// in the level ("" if unknown).
+func (d *Defs) EntityIID(level *ldtkgo.Level, layerIndex, index int) string {
+	if d == nil {
+		return ""
+	}
+	layers := d.entityIIDs[level.Identifier]
+	if layerIndex >= len(layers) || index >= len(layers[layerIndex]) {
+		return ""
+	}
+	return layers[layerIndex][index]
+}
+
+// LevelIdentifier returns the identifier of the level with the iid ("" if unknown).
+func (d *Defs) LevelIdentifier(iid string) string {
+	if d == nil {
+		return ""
+	}
+	return d.Levels[iid]
+}
+
+// IntGridIdentifier returns the identifier of an IntGrid value of a layer ("" if unnamed).
+func (d *Defs) IntGridIdentifier(layer string, value int) string {
+	if d == nil {
+		return ""
+	}
+	return d.IntGrid[layer][value]
+}
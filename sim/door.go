@@ -0,0 +1,226 @@
+package sim
+
+import (
+	"slices"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// Door, spawn point and trigger entities identifiers; fields:
+//
+//	Door:
+//	  target  Entity ref, the spawn point (or door) the player arrives at, in any level
+//	  level   String, the target level identifier, when there is no target ref (this level if empty)
+//	  spawn   String, the id of the target spawn point in that level
+//	  auto    Bool, entered by touching it (a level exit) instead of pressing Up
+//	Spawn, PlayerStart:
+//	  id      String (PlayerStart is where the player starts the level)
+//	Trigger:
+//	  event   String, the event name handled by the game ("show_message", "start_cutscene", "switch_music"...)
+//	  arg     String, the event argument (message text, cutscene or music name)
+//	  once    Bool, fires its enter event only the first time
+const (
+	EntityDoor        = "Door"
+	EntitySpawn       = "Spawn"
+	EntityPlayerStart = "PlayerStart"
+	EntityTrigger     = "Trigger"
+)
+
+// ----------------------------------------------------------------------------- Door struct
+type Door struct {
+	Rect[float64]
+	IID  string
+	ID   string
+	Auto bool
+	// where it leads: the level identifier ("" for the same level) and the
+	// iid or id of the spawn point, door or checkpoint there
+	TargetLevel string
+	TargetSpawn string
+}
+
+func NewDoor(e *ldtkgo.Entity, layer *ldtkgo.Layer, iid string, defs *Defs) *Door {
+	x, y, w, h := entityBounds(e, layer)
+	d := &Door{
+		Rect:        Rect[float64]{x, y, w, h},
+		IID:         iid,
+		ID:          entityID(e, layer),
+		Auto:        propBool(e, "auto", false),
+		TargetLevel: propString(e, "level", ""),
+		TargetSpawn: propString(e, "spawn", ""),
+	}
+	if ref, ok := propRef(e, "target"); ok {
+		d.TargetLevel = defs.LevelIdentifier(ref.LevelIID)
+		d.TargetSpawn = ref.EntityIID
+	}
+	return d
+}
+
+// ----------------------------------------------------------------------------- SpawnPoint struct
+type SpawnPoint struct {
+	Rect[float64]
+	IID   string
+	ID    string
+	Start bool // the level start
+}
+
+func NewSpawnPoint(e *ldtkgo.Entity, layer *ldtkgo.Layer, iid string) *SpawnPoint {
+	x, y, w, h := entityBounds(e, layer)
+	return &SpawnPoint{
+		Rect:  Rect[float64]{x, y, w, h},
+		IID:   iid,
+		ID:    entityID(e, layer),
+		Start: e.Identifier == EntityPlayerStart,
+	}
+}
+
+// standingOn returns the player position standing on the bottom center of the box
+func standingOn(r Rect[float64]) Vec2D[float64] {
+	return Vec2D[float64]{r.Center().X - HitboxW/2 - HitboxX, r.Bottom() - HitboxH - HitboxY}
+}
+
+// ----------------------------------------------------------------------------- Trigger struct
+type TriggerPhase int
+
+const (
+	Trigger_Enter TriggerPhase = iota
+	Trigger_Stay
+	Trigger_Exit
+)
+
+func (t TriggerPhase) String() string {
+	return [...]string{"Enter", "Stay", "Exit"}[t]
+}
+
+// Trigger is a zone firing its event when the player enters it, stays in it
+// and leaves it
+type Trigger struct {
+	Rect[float64]
+	IID    string
+	ID     string
+	Event  string
+	Arg    string
+	Once   bool
+	Inside bool
+	Fired  int // times entered
+}
+
+func NewTrigger(e *ldtkgo.Entity, layer *ldtkgo.Layer, iid string) *Trigger {
+	x, y, w, h := entityBounds(e, layer)
+	return &Trigger{
+		Rect:  Rect[float64]{x, y, w, h},
+		IID:   iid,
+		ID:    entityID(e, layer),
+		Event: propString(e, "event", ""),
+		Arg:   propString(e, "arg", ""),
+		Once:  propBool(e, "once", false),
+	}
+}
+
+// ----------------------------------------------------------------------------- World
+// newZones builds the doors, spawn points and triggers of the level
+func (w *World) newZones(defs *Defs) {
+	levelEntities(w.Level, defs, func(e *ldtkgo.Entity, layer *ldtkgo.Layer, iid string) {
+		switch e.Identifier {
+		case EntityDoor:
+			w.Doors = append(w.Doors, NewDoor(e, layer, iid, defs))
+		case EntitySpawn, EntityPlayerStart:
+			w.SpawnPoints = append(w.SpawnPoints, NewSpawnPoint(e, layer, iid))
+		case EntityTrigger:
+			w.Triggers = append(w.Triggers, NewTrigger(e, layer, iid))
+		}
+	})
+	w.DoorZones = NewSpatialHash[*Door](w.Items.CellSize)
+	for _, d := range w.Doors {
+		w.DoorZones.Insert(d, d.Rect)
+	}
+	w.TriggerZones = NewSpatialHash[*Trigger](w.Items.CellSize)
+	for _, t := range w.Triggers {
+		w.TriggerZones.Insert(t, t.Rect)
+	}
+	for _, s := range w.SpawnPoints {
+		if s.Start {
+			w.PlacePlayer(standingOn(s.Rect))
+			w.Spawn = w.Player.Pos
+		}
+	}
+}
+
+// PlacePlayer moves the player, e.g. at the door it comes from. The doors and
+// triggers it lands in do not fire until it leaves them.
+func (w *World) PlacePlayer(pos Vec2D[float64]) {
+	w.Player.Pos = pos
+	w.Player.Velocity = Vec2D[float64]{}
+	box := w.Player.Bounds()
+	w.atDoors = w.DoorZones.Query(box)
+	for _, t := range w.inTriggers {
+		t.Inside = false
+	}
+	w.inTriggers = w.TriggerZones.Query(box)
+	for _, t := range w.inTriggers {
+		t.Inside = true
+	}
+	w.upHeld = true
+}
+
+// SpawnAt returns the player position at the spawn point, door or checkpoint
+// with the iid or id.
+func (w *World) SpawnAt(ref string) (Vec2D[float64], bool) {
+	for _, s := range w.SpawnPoints {
+		if s.IID == ref || s.ID == ref {
+			return standingOn(s.Rect), true
+		}
+	}
+	for _, d := range w.Doors {
+		if d.IID == ref || d.ID == ref {
+			return standingOn(d.Rect), true
+		}
+	}
+	for _, c := range w.Checkpoints {
+		if c.ID == ref {
+			return c.Spawn(), true
+		}
+	}
+	return Vec2D[float64]{}, false
+}
+
+// touchZones fires the triggers and enters the doors the player touches
+func (w *World) touchZones(in Input) {
+	box := w.Player.Bounds()
+	touched := w.TriggerZones.Query(box)
+	// left since the last tick
+	for _, t := range w.inTriggers {
+		if !slices.Contains(touched, t) {
+			t.Inside = false
+			w.fireTrigger(t, Trigger_Exit)
+		}
+	}
+	for _, t := range touched {
+		phase := Trigger_Stay
+		if !t.Inside {
+			phase = Trigger_Enter
+			t.Inside = true
+			t.Fired += 1
+		}
+		w.fireTrigger(t, phase)
+	}
+	w.inTriggers = touched
+
+	// auto doors are entered when the player comes in only
+	doors := w.DoorZones.Query(box)
+	for _, d := range doors {
+		entered := !slices.Contains(w.atDoors, d)
+		if (d.Auto && entered) || (!d.Auto && in.Up && !w.upHeld && w.Player.Grounded) {
+			w.Events.Post(DoorEntered{Door: d})
+			break
+		}
+	}
+	w.atDoors = doors
+	w.upHeld = in.Up
+}
+
+func (w *World) fireTrigger(t *Trigger, phase TriggerPhase) {
+	if t.Once && t.Fired > 1 {
+		return
+	}
+	w.Events.Publish(TriggerFired{Trigger: t, Phase: phase})
+}
@@ -0,0 +1,76 @@
+package sim
+
+import (
+	"reflect"
+	"testing"
+)
+
+// zoneWorld is a world with only the zones, for touchZones
+func zoneWorld(doors []*Door, triggers []*Trigger) *World {
+	w := &World{
+		Player:       NewPlayer(),
+		Doors:        doors,
+		Triggers:     triggers,
+		DoorZones:    NewSpatialHash[*Door](64),
+		TriggerZones: NewSpatialHash[*Trigger](64),
+		Events:       NewEventBus(),
+	}
+	for _, d := range doors {
+		w.DoorZones.Insert(d, d.Rect)
+	}
+	for _, t := range triggers {
+		w.TriggerZones.Insert(t, t.Rect)
+	}
+	return w
+}
+
+// moveTo moves the player hitbox to x, y and touches the zones
+func moveTo(w *World, x, y float64, in Input) {
+	w.Player.Pos = Vec2D[float64]{x - HitboxX, y - HitboxY}
+	w.touchZones(in)
+}
+
+func TestTriggerPhases(t *testing.T) {
+	once := &Trigger{Rect: Rect[float64]{100, 0, 50, 50}, ID: "once", Once: true}
+	always := &Trigger{Rect: Rect[float64]{300, 0, 50, 50}, ID: "always"}
+	w := zoneWorld(nil, []*Trigger{once, always})
+	fired := []string{}
+	Subscribe(w.Events, func(e TriggerFired) { fired = append(fired, e.Trigger.ID+" "+e.Phase.String()) })
+
+	for _, x := range []float64{0, 110, 120, 200, 110, 200, 310, 500} {
+		moveTo(w, x, 10, Input{})
+	}
+	want := []string{
+		"once Enter", "once Stay", "once Exit",
+		"always Enter", "always Exit",
+	}
+	if !reflect.DeepEqual(fired, want) {
+		t.Errorf("fired %v, want %v", fired, want)
+	}
+	if once.Fired != 2 || once.Inside || always.Inside {
+		t.Errorf("once fired %d inside %v, always inside %v", once.Fired, once.Inside, always.Inside)
+	}
+}
+
+func TestDoors(t *testing.T) {
+	auto := &Door{Rect: Rect[float64]{100, 0, 50, 50}, ID: "exit", Auto: true}
+	door := &Door{Rect: Rect[float64]{300, 0, 50, 50}, ID: "door"}
+	w := zoneWorld([]*Door{auto, door}, nil)
+	entered := []string{}
+	Subscribe(w.Events, func(e DoorEntered) { entered = append(entered, e.Door.ID) })
+	w.Player.Grounded = true
+
+	// placed in the auto door, entered only once out and back in
+	w.PlacePlayer(Vec2D[float64]{110 - HitboxX, 10 - HitboxY})
+	moveTo(w, 110, 10, Input{})
+	moveTo(w, 0, 10, Input{})
+	moveTo(w, 110, 10, Input{})
+	moveTo(w, 120, 10, Input{})
+	// Up pressed in the door, then held
+	moveTo(w, 310, 10, Input{Up: true})
+	moveTo(w, 310, 10, Input{Up: true})
+	w.Events.Flush()
+	if want := []string{"exit", "door"}; !reflect.DeepEqual(entered, want) {
+		t.Errorf("entered %v, want %v", entered, want)
+	}
+}
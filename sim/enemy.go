@@ -0,0 +1,498 @@
+package sim
+
+import (
+	"math"
+	"slices"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// ------------------------------------------------
+type EnemyState int
+
+const (
+	Enemy_Patrol EnemyState = iota // walk between the patrol points (or to the next wall/ledge)
+	Enemy_Chase                    // walk towards the player in range
+	Enemy_Attack                   // wind up, then lunge at the player
+	Enemy_Hurt                     // knocked back, no AI
+	Enemy_Dead                     // removed after EnemyDeadTicks
+)
+
+func (s EnemyState) String() string {
+	return [...]string{"Patrol", "Chase", "Attack", "Hurt", "Dead"}[s]
+}
+
+// Enemy entity identifier; fields:
+//
+//	kind         String, the look of the enemy (the entity identifier by default)
+//	patrol       Point array, the points walked through back and forth (starting from its position)
+//	speed        Float, patrol speed in pixels per tick
+//	chase_speed  Float
+//	chase_range  Float, pixels from the player to chase it
+//	attack_range Float, pixels from the player to attack it
+//	health       Int
+//	stompable    Bool, hurt by jumping on it (true by default)
+//	flying       Bool, no gravity, chases the player around the walls
+const EntityEnemy = "Enemy"
+
+const (
+	EnemySpeed        = 0.5
+	EnemyChaseSpeed   = 1.0
+	EnemyChaseRange   = 96.0
+	EnemyAttackRange  = 24.0
+	EnemyHealth       = 1
+	EnemyGravity      = 0.25
+	EnemyMaxFall      = 6.0
+	EnemyAttackWindup = 20 // ticks standing still before the lunge
+	EnemyAttackTicks  = 45
+	EnemyLunge        = 2.5
+	EnemyHurtTicks    = 30
+	EnemyDeadTicks    = 30
+	EnemyKnockback    = 2.0
+	EnemyRepathTicks  = 15 // ticks between two path searches
+	EnemyMaxFallCells = 4  // highest ledge a walker jumps down from to chase the player
+	EnemyJumpCells    = 3  // jump height of a walker, up to ledges one cell lower
+	EnemyJumpDistance = 3  // widest jump of a walker, in cells
+	// how far below the enemy top the player feet may be for a stomp
+	StompMargin = 6.0
+	StompBounce = 4.0
+)
+
+// ----------------------------------------------------------------------------- Enemy struct
+type Enemy struct {
+	Rect[float64] // hitbox
+	Kind          string
+	Velocity      Vec2D[float64]
+	Dir           Dir
+	State         EnemyState
+	Health        int
+	Grounded      bool
+	Active        bool
+	Patrol        []Vec2D[float64] // hitbox top-left positions
+	Speed         float64
+	ChaseSpeed    float64
+	ChaseRange    float64
+	AttackRange   float64
+	Stompable     bool
+	Flying        bool
+	// state
+	target  int
+	step    int // +1 / -1 along the patrol points
+	timer   int // ticks left in the attack, hurt and dead states
+	path    []PathStep
+	repath  int     // ticks left before the next path search
+	jumping bool    // a walker in the air from a path jump
+	jumpX   float64 // its horizontal speed, kept after bumping on the ledge
+}
+
+// NewEnemy builds an enemy from its LDtk entity
+func NewEnemy(e *ldtkgo.Entity, layer *ldtkgo.Layer) *Enemy {
+	x, y, w, h := entityBounds(e, layer)
+	en := &Enemy{
+		Rect:        Rect[float64]{x, y, w, h},
+		Kind:        propString(e, "kind", e.Identifier),
+		Dir:         Dir_Right,
+		State:       Enemy_Patrol,
+		Health:      propInt(e, "health", EnemyHealth),
+		Active:      true,
+		Speed:       propFloat(e, "speed", EnemySpeed),
+		ChaseSpeed:  propFloat(e, "chase_speed", EnemyChaseSpeed),
+		ChaseRange:  propFloat(e, "chase_range", EnemyChaseRange),
+		AttackRange: propFloat(e, "attack_range", EnemyAttackRange),
+		Stompable:   propBool(e, "stompable", true),
+		Flying:      propBool(e, "flying", false),
+		step:        1,
+	}
+	en.Patrol = append([]Vec2D[float64]{{x, y}}, propPoints(e, "patrol", layer)...)
+	if len(en.Patrol) > 1 {
+		en.target = 1
+	}
+	return en
+}
+
+// NewEnemies builds the enemies of all the level enemy entities
+func NewEnemies(level *ldtkgo.Level) []*Enemy {
+	enemies := []*Enemy{}
+	entities(level, func(e *ldtkgo.Entity, layer *ldtkgo.Layer) {
+		if e.Identifier == EntityEnemy {
+			enemies = append(enemies, NewEnemy(e, layer))
+		}
+	})
+	return enemies
+}
+
+// Hurt takes one point of health and knocks the enemy back, away from the world x
+func (e *Enemy) Hurt(from float64) {
+	if e.State == Enemy_Hurt || e.State == Enemy_Dead {
+		return
+	}
+	e.Health -= 1
+	e.Velocity = Vec2D[float64]{EnemyKnockback, -EnemyKnockback}
+	if from > e.Center().X {
+		e.Velocity.X = -EnemyKnockback
+	}
+	if e.Health <= 0 {
+		e.State = Enemy_Dead
+		e.timer = EnemyDeadTicks
+	} else {
+		e.State = Enemy_Hurt
+		e.timer = EnemyHurtTicks
+	}
+}
+
+// Harmful reports whether touching the enemy hurts the player
+func (e *Enemy) Harmful() bool {
+	return e.Active && e.State != Enemy_Hurt && e.State != Enemy_Dead
+}
+
+// Update runs the AI of the enemy and moves it
+func (e *Enemy) Update(player *Player, paths *Pathfinder) {
+	if !e.Active {
+		return
+	}
+	grid := paths.Grid
+	if e.timer > 0 {
+		e.timer -= 1
+	}
+
+	switch e.State {
+	case Enemy_Dead:
+		if e.timer == 0 {
+			e.Active = false
+			return
+		}
+		e.Velocity.X *= 0.9
+	case Enemy_Hurt:
+		e.Velocity.X *= 0.9
+		if e.Flying {
+			e.Velocity.Y *= 0.9
+		}
+		if e.timer == 0 {
+			e.State = Enemy_Patrol
+		}
+	case Enemy_Attack:
+		switch {
+		case e.timer > EnemyAttackTicks-EnemyAttackWindup:
+			e.Velocity.X = 0
+		case e.timer == EnemyAttackTicks-EnemyAttackWindup:
+			e.Velocity.X = e.dirX() * EnemyLunge
+			if e.Flying {
+				e.Velocity = player.Bounds().Center().Sub(e.Center()).Normalize().Scale(EnemyLunge)
+			}
+		default:
+			e.Velocity = e.Velocity.Scale(0.9)
+		}
+		if !e.Flying && e.Grounded && e.blocked(grid) {
+			e.Velocity.X = 0
+		}
+		if e.timer == 0 {
+			e.State = Enemy_Chase
+		}
+	default:
+		if e.Flying {
+			e.fly(player, paths)
+		} else {
+			e.think(player, paths)
+		}
+	}
+
+	if e.Flying && e.State != Enemy_Dead {
+		e.moveX(grid)
+		e.moveFlying(grid)
+		return
+	}
+	e.Velocity.Y = math.Min(e.Velocity.Y+EnemyGravity, EnemyMaxFall)
+	e.moveX(grid)
+	e.moveY(grid)
+}
+
+// patrol, or chase and attack the player in range
+func (e *Enemy) think(player *Player, paths *Pathfinder) {
+	grid := paths.Grid
+	to := player.Bounds().Center().Sub(e.Center())
+	if !player.Dead() && math.Abs(to.X) <= e.ChaseRange && math.Abs(to.Y) <= e.ChaseRange/2 {
+		e.State = Enemy_Chase
+		if e.jumping && !e.Grounded {
+			e.Velocity.X = e.jumpX
+			return
+		}
+		e.jumping = false
+		e.face(to.X)
+		if math.Abs(to.X) <= e.AttackRange && e.Grounded {
+			e.State = Enemy_Attack
+			e.timer = EnemyAttackTicks
+			e.Velocity.X = 0
+			return
+		}
+		if e.Grounded && e.followPath(player, paths) {
+			return
+		}
+		e.Velocity.X = e.dirX() * e.ChaseSpeed
+		if e.Grounded && e.blocked(grid) {
+			// no way there, wait at the edge
+			e.Velocity.X = 0
+		}
+		return
+	}
+
+	e.State = Enemy_Patrol
+	e.path = nil
+	e.jumping = false
+	if len(e.Patrol) > 1 {
+		dx := e.Patrol[e.target].X - e.X
+		if math.Abs(dx) <= e.Speed {
+			e.nextTarget()
+			dx = e.Patrol[e.target].X - e.X
+		}
+		e.face(dx)
+	}
+	if e.Grounded && e.blocked(grid) {
+		e.turn()
+	}
+	e.Velocity.X = e.dirX() * e.Speed
+}
+
+// agent is the walker size and moves for the pathfinder
+func (e *Enemy) agent(paths *Pathfinder) PathAgent {
+	return PathAgent{
+		Height:       int(math.Ceil(e.H / float64(paths.Grid.CellSize))),
+		JumpHeight:   EnemyJumpCells,
+		JumpDistance: EnemyJumpDistance,
+		MaxFall:      EnemyMaxFallCells,
+	}
+}
+
+// standingCell returns the cell of the walker feet
+func (e *Enemy) standingCell(paths *Pathfinder) Vec2D[int] {
+	return paths.CellOf(Vec2D[float64]{e.Center().X, e.Bottom() - collisionEps})
+}
+
+// followPath walks, falls and jumps along the walk path to the player; false
+// when there is no path (or the path ends here)
+func (e *Enemy) followPath(player *Player, paths *Pathfinder) bool {
+	from := e.standingCell(paths)
+	if e.repath > 0 {
+		e.repath -= 1
+	}
+	// search from and to standing cells only (not over a ledge, the player not
+	// in the air), keeping the path meanwhile
+	agent := e.agent(paths)
+	if (e.path == nil || (e.repath == 0 && player.Grounded)) && paths.Standable(from, agent) {
+		pb := player.Bounds()
+		to := paths.CellOf(Vec2D[float64]{pb.Center().X, pb.Bottom() - collisionEps})
+		e.path = paths.WalkPath(from, to, agent)
+		e.repath = EnemyRepathTicks
+	}
+	// drop the steps behind, search again when off the path (not on a step,
+	// nor on the way to the next one, e.g. stepping off a ledge)
+	if i := slices.IndexFunc(e.path, func(s PathStep) bool { return s.Cell == from }); i >= 0 {
+		e.path = e.path[i:]
+	} else if len(e.path) < 2 || !between(from.X, e.path[0].Cell.X, e.path[1].Cell.X) {
+		e.path = nil
+		return false
+	}
+	if len(e.path) < 2 {
+		return false
+	}
+
+	next := e.path[1]
+	x := e.Center().X
+	if next.Move == Move_Jump {
+		// jump from the center of the cell
+		if start := paths.CellCenter(from).X; math.Abs(start-x) > e.ChaseSpeed {
+			e.face(start - x)
+			e.Velocity.X = e.dirX() * e.ChaseSpeed
+			return true
+		}
+		e.jump(from, next.Cell, paths)
+		return true
+	}
+	dx := paths.CellCenter(next.Cell).X - x
+	e.face(dx)
+	e.Velocity.X = math.Copysign(math.Min(math.Abs(dx), e.ChaseSpeed), dx)
+	return true
+}
+
+// between reports whether x is from a to b, in either order
+func between(x, a, b int) bool {
+	return x >= min(a, b) && x <= max(a, b)
+}
+
+// jump leaps from the cell to the other one, over the arc checked by the
+// pathfinder (the feet one cell above the highest end)
+func (e *Enemy) jump(from, to Vec2D[int], paths *Pathfinder) {
+	cs := float64(paths.Grid.CellSize)
+	rise := float64(from.Y-min(from.Y, to.Y)+1)*cs + 1
+	vy := math.Sqrt(2 * EnemyGravity * rise)
+	drop := float64(to.Y-from.Y) * cs
+	ticks := (vy + math.Sqrt(vy*vy+2*EnemyGravity*drop)) / EnemyGravity
+	dx := paths.CellCenter(to).X - e.Center().X
+	e.face(dx)
+	e.jumpX = dx / ticks
+	e.Velocity = Vec2D[float64]{e.jumpX, -vy}
+	e.Grounded = false
+	e.jumping = true
+}
+
+// flying: patrol between the points, or follow a path to the player in range
+func (e *Enemy) fly(player *Player, paths *Pathfinder) {
+	to := player.Bounds().Center().Sub(e.Center())
+	if player.Dead() || to.Length() > e.ChaseRange {
+		e.State = Enemy_Patrol
+		e.path = nil
+		e.Velocity = Vec2D[float64]{}
+		if len(e.Patrol) > 1 {
+			d := e.Patrol[e.target].Sub(e.Pos())
+			if d.Length() <= e.Speed {
+				e.nextTarget()
+				d = e.Patrol[e.target].Sub(e.Pos())
+			}
+			e.Velocity = d.Normalize().Scale(e.Speed)
+			e.face(d.X)
+		}
+		return
+	}
+
+	e.State = Enemy_Chase
+	e.face(to.X)
+	if to.Length() <= e.AttackRange {
+		e.State = Enemy_Attack
+		e.timer = EnemyAttackTicks
+		e.Velocity = Vec2D[float64]{}
+		return
+	}
+	if e.repath > 0 {
+		e.repath -= 1
+	}
+	if e.path == nil || e.repath == 0 {
+		e.path = paths.FlyPath(paths.CellOf(e.Center()), paths.CellOf(player.Bounds().Center()), true)
+		e.repath = EnemyRepathTicks
+	}
+	// head to the next cell of the path, or straight to the player at its end
+	goal := player.Bounds().Center()
+	for len(e.path) > 2 {
+		next := paths.CellCenter(e.path[1].Cell)
+		if next.Distance(e.Center()) > e.ChaseSpeed {
+			goal = next
+			break
+		}
+		e.path = e.path[1:]
+	}
+	e.Velocity = goal.Sub(e.Center()).Normalize().Scale(e.ChaseSpeed)
+}
+
+func (e *Enemy) nextTarget() {
+	if e.target+e.step < 0 || e.target+e.step >= len(e.Patrol) {
+		e.step = -e.step
+	}
+	e.target += e.step
+}
+
+func (e *Enemy) face(dx float64) {
+	if dx > 0 {
+		e.Dir = Dir_Right
+	} else if dx < 0 {
+		e.Dir = Dir_Left
+	}
+}
+
+// turn around, heading to the previous patrol point
+func (e *Enemy) turn() {
+	if e.Dir == Dir_Right {
+		e.Dir = Dir_Left
+	} else {
+		e.Dir = Dir_Right
+	}
+	if len(e.Patrol) > 1 {
+		e.step = -e.step
+		e.nextTarget()
+	}
+}
+
+func (e *Enemy) dirX() float64 {
+	if e.Dir == Dir_Left {
+		return -1
+	}
+	return 1
+}
+
+// blocked reports whether there is a wall or a ledge just ahead
+func (e *Enemy) blocked(grid *CollisionGrid) bool {
+	step := math.Max(e.Speed, e.ChaseSpeed) + 1
+	x := e.Right()
+	if e.Dir == Dir_Left {
+		x = e.X - 1
+	}
+	if grid.Overlaps(x, e.Y, 1, e.H-step) {
+		return true
+	}
+	_, ground := grid.GroundBelow(x, e.Y-step, 1, e.H, 2*step)
+	return !ground
+}
+
+func (e *Enemy) moveX(grid *CollisionGrid) {
+	dx := e.Velocity.X
+	e.X += dx
+	step := math.Abs(dx) + 1
+	if e.Flying {
+		step = 0
+	}
+	if dx == 0 || !grid.Overlaps(e.X, e.Y, e.W, e.H-step) {
+		return
+	}
+	cs := float64(grid.CellSize)
+	if dx > 0 {
+		e.X = float64(grid.ToCell(e.Right()))*cs - e.W
+	} else {
+		e.X = float64(grid.ToCell(e.X)+1) * cs
+	}
+	e.Velocity.X = 0
+	if e.State == Enemy_Patrol && !e.Flying {
+		e.turn()
+	}
+}
+
+// move vertically without gravity, stopped by the walls
+func (e *Enemy) moveFlying(grid *CollisionGrid) {
+	dy := e.Velocity.Y
+	e.Y += dy
+	e.Grounded = false
+	if dy == 0 || !grid.Overlaps(e.X, e.Y, e.W, e.H) {
+		return
+	}
+	cs := float64(grid.CellSize)
+	if dy > 0 {
+		e.Y = float64(grid.ToCell(e.Bottom()))*cs - e.H
+	} else {
+		e.Y = float64(grid.ToCell(e.Y)+1) * cs
+	}
+	e.Velocity.Y = 0
+}
+
+// fall and land on the ground under the hitbox, following slopes up and down
+// by a few pixels
+func (e *Enemy) moveY(grid *CollisionGrid) {
+	dy := e.Velocity.Y
+	if dy < 0 {
+		e.Y += dy
+		if grid.Overlaps(e.X, e.Y, e.W, e.H) {
+			e.Y = float64(grid.ToCell(e.Y)+1) * float64(grid.CellSize)
+			e.Velocity.Y = 0
+		}
+		e.Grounded = false
+		return
+	}
+	step := math.Abs(e.Velocity.X) + 1
+	reach := dy
+	if e.Grounded {
+		reach = math.Max(dy, step)
+	}
+	if floor, ok := grid.GroundBelow(e.X, e.Y-step, e.W, e.H, step+reach); ok {
+		e.Y = floor - e.H
+		e.Velocity.Y = 0
+		e.Grounded = true
+		return
+	}
+	e.Y += dy
+	e.Grounded = false
+}
@@ -0,0 +1,91 @@
+package sim
+
+import "testing"
+
+// testWalker is a 16x16 walker standing in the cell
+func testWalker(cell Vec2D[int]) *Enemy {
+	return &Enemy{
+		Rect:        Rect[float64]{float64(cell.X * 16), float64(cell.Y * 16), 16, 16},
+		State:       Enemy_Patrol,
+		Health:      1,
+		Active:      true,
+		Speed:       EnemySpeed,
+		ChaseSpeed:  EnemyChaseSpeed,
+		ChaseRange:  EnemyChaseRange,
+		AttackRange: 4,
+		step:        1,
+	}
+}
+
+// testPlayer is the player standing in the cell
+func testPlayer(cell Vec2D[int]) *Player {
+	p := NewPlayer()
+	p.Pos = Vec2D[float64]{float64(cell.X*16+8) - HitboxW/2 - HitboxX, float64(cell.Y*16+16) - HitboxH - HitboxY}
+	p.Grounded = true
+	return p
+}
+
+func TestWalkerJumpsOnLedge(t *testing.T) {
+	pf := NewPathfinder(testGrid(
+		"..........",
+		"..........",
+		"..........",
+		"......####",
+		"......####",
+		"##########",
+	))
+	e := testWalker(Vec2D[int]{2, 4})
+	player := testPlayer(Vec2D[int]{8, 2})
+	jumped := false
+	for i := 0; i < 300; i++ {
+		e.Update(player, pf)
+		jumped = jumped || e.jumping
+		if e.Grounded && e.standingCell(pf).Y == 2 {
+			break
+		}
+	}
+	if !jumped {
+		t.Error("the walker did not jump")
+	}
+	if c := e.standingCell(pf); !e.Grounded || c.Y != 2 {
+		t.Errorf("walker at %v (cell %v, grounded %v), want on the ledge", e.Pos(), c, e.Grounded)
+	}
+}
+
+func TestWalkerJumpsOverGap(t *testing.T) {
+	pf := NewPathfinder(testGrid(
+		"............",
+		"............",
+		"............",
+		"............",
+		"#####..#####",
+		"#####..#####",
+	))
+	e := testWalker(Vec2D[int]{1, 3})
+	player := testPlayer(Vec2D[int]{9, 3})
+	for i := 0; i < 300 && e.X < 7*16; i++ {
+		e.Update(player, pf)
+	}
+	if c := e.standingCell(pf); c.Y != 3 || c.X < 7 {
+		t.Errorf("walker at %v (cell %v), want across the gap", e.Pos(), c)
+	}
+}
+
+func TestWalkerDropsFromLedge(t *testing.T) {
+	pf := NewPathfinder(testGrid(
+		"..........",
+		"..........",
+		"####......",
+		"####......",
+		"####......",
+		"##########",
+	))
+	e := testWalker(Vec2D[int]{1, 1})
+	player := testPlayer(Vec2D[int]{7, 4})
+	for i := 0; i < 300 && !(e.Grounded && e.standingCell(pf).Y == 4); i++ {
+		e.Update(player, pf)
+	}
+	if c := e.standingCell(pf); !e.Grounded || c.Y != 4 {
+		t.Errorf("walker at %v (cell %v), want down on the floor", e.Pos(), c)
+	}
+}
@@ -0,0 +1,130 @@
+package sim
+
+import (
+	"strings"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// Helpers reading the LDtk entity fields; missing or null fields give the default.
+// Field identifiers are matched case insensitively.
+
+func property(e *ldtkgo.Entity, id string) *ldtkgo.Property {
+	for _, p := range e.Properties {
+		if strings.EqualFold(p.Identifier, id) && !p.IsNull() {
+			return p
+		}
+	}
+	return nil
+}
+
+func propFloat(e *ldtkgo.Entity, id string, def float64) float64 {
+	if p := property(e, id); p != nil {
+		if v, ok := p.Value.(float64); ok {
+			return v
+		}
+	}
+	return def
+}
+
+func propInt(e *ldtkgo.Entity, id string, def int) int {
+	return int(propFloat(e, id, float64(def)))
+}
+
+func propString(e *ldtkgo.Entity, id string, def string) string {
+	if p := property(e, id); p != nil {
+		if v, ok := p.Value.(string); ok {
+			return v
+		}
+	}
+	return def
+}
+
+func propBool(e *ldtkgo.Entity, id string, def bool) bool {
+	if p := property(e, id); p != nil {
+		if v, ok := p.Value.(bool); ok {
+			return v
+		}
+	}
+	return def
+}
+
+// propPoints reads a Point (or Point array) field as world positions of the
+// top-left of the cells (LDtk points are in grid cells of the layer, moved by
+// its offset like the entities).
+func propPoints(e *ldtkgo.Entity, id string, layer *ldtkgo.Layer) []Vec2D[float64] {
+	p := property(e, id)
+	if p == nil {
+		return nil
+	}
+	values, ok := p.Value.([]interface{})
+	if !ok {
+		values = []interface{}{p.Value}
+	}
+	points := []Vec2D[float64]{}
+	for _, v := range values {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		cx, _ := m["cx"].(float64)
+		cy, _ := m["cy"].(float64)
+		size := float64(layer.GridSize)
+		points = append(points, Vec2D[float64]{cx*size + float64(layer.OffsetX), cy*size + float64(layer.OffsetY)})
+	}
+	return points
+}
+
+// entityBounds returns the entity box in world coordinates (its position is the pivot)
+func entityBounds(e *ldtkgo.Entity, layer *ldtkgo.Layer) (float64, float64, float64, float64) {
+	px, py := 0., 0.
+	if len(e.Pivot) == 2 {
+		px, py = float64(e.Pivot[0]), float64(e.Pivot[1])
+	}
+	x := float64(e.Position[0]+layer.OffsetX) - px*float64(e.Width)
+	y := float64(e.Position[1]+layer.OffsetY) - py*float64(e.Height)
+	return x, y, float64(e.Width), float64(e.Height)
+}
+
+// entities calls fn for every entity of the level (in all the entity layers)
+func entities(level *ldtkgo.Level, fn func(*ldtkgo.Entity, *ldtkgo.Layer)) {
+	for _, layer := range level.Layers {
+		if layer.Type != ldtkgo.LayerTypeEntity {
+			continue
+		}
+		for _, e := range layer.Entities {
+			fn(e, layer)
+		}
+	}
+}
+
+// EntityRef is the value of an LDtk entity reference field
+type EntityRef struct {
+	EntityIID string
+	LevelIID  string
+}
+
+func propRef(e *ldtkgo.Entity, id string) (EntityRef, bool) {
+	if p := property(e, id); p != nil {
+		if m, ok := p.Value.(map[string]interface{}); ok {
+			ref := EntityRef{}
+			ref.EntityIID, _ = m["entityIid"].(string)
+			ref.LevelIID, _ = m["levelIid"].(string)
+			return ref, ref.EntityIID != ""
+		}
+	}
+	return EntityRef{}, false
+}
+
+// levelEntities calls fn for every entity of the level with its iid (from defs,
+// "" when unknown)
+func levelEntities(level *ldtkgo.Level, defs *Defs, fn func(*ldtkgo.Entity, *ldtkgo.Layer, string)) {
+	for li, layer := range level.Layers {
+		if layer.Type != ldtkgo.LayerTypeEntity {
+			continue
+		}
+		for i, e := range layer.Entities {
+			fn(e, layer, defs.EntityIID(level, li, i))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package sim
+
+import (
+	"testing"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+func TestPropPointsOffset(t *testing.T) {
+	layer := &ldtkgo.Layer{Type: ldtkgo.LayerTypeEntity, GridSize: 16, OffsetX: 8, OffsetY: -4}
+	e := &ldtkgo.Entity{
+		Identifier: EntityMovingPlatform,
+		Position:   []int{32, 48},
+		Width:      32,
+		Height:     8,
+		Pivot:      []float32{0, 0},
+		Properties: []*ldtkgo.Property{{
+			Identifier: "path",
+			Value:      []interface{}{map[string]interface{}{"cx": 2., "cy": 3.}, map[string]interface{}{"cx": 5., "cy": 3.}},
+		}},
+	}
+	p := NewPlatform(e, layer)
+	want := []Vec2D[float64]{{40, 44}, {40, 44}, {88, 44}}
+	if len(p.Path) != len(want) {
+		t.Fatalf("path %v, want %v", p.Path, want)
+	}
+	for i := range want {
+		if p.Path[i] != want[i] {
+			t.Errorf("path %v, want %v: the points are not moved like the entity", p.Path, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package sim
+
+import (
+	"reflect"
+)
+
+// ----------------------------------------------------------------------------- events
+// the world events; they are values, the subscribers must not keep the pointers
+// they carry past the level
+type (
+	PlayerJumped struct {
+		Pos Vec2D[float64]
+	}
+	PlayerLanded struct {
+		Pos      Vec2D[float64]
+		Speed    float64 // fall speed when landing
+		Material string  // of the ground tile, see LevelTiles.MaterialAt
+	}
+	PlayerHit struct {
+		Pos    Vec2D[float64]
+		Health int // left
+	}
+	PlayerDied struct {
+		Pos Vec2D[float64]
+	}
+	// back to life at the spawn point
+	PlayerRespawned struct {
+		Pos Vec2D[float64]
+	}
+	// the enemy state changed (chasing, attacking, hurt, dead...)
+	EnemyChanged struct {
+		Enemy *Enemy
+		From  EnemyState
+	}
+	ItemCollected struct {
+		Level  string // identifier
+		Pickup *Pickup
+	}
+	CheckpointReached struct {
+		Level      string
+		Checkpoint *Checkpoint
+	}
+	// posted, delivered at the end of the tick as entering a door may change the level
+	DoorEntered struct {
+		Door *Door
+	}
+	TriggerFired struct {
+		Trigger *Trigger
+		Phase   TriggerPhase
+	}
+	// published by the game when a level is (re)loaded
+	LevelEntered struct {
+		Level string
+		Index int
+	}
+)
+
+// ----------------------------------------------------------------------------- EventBus struct
+// EventBus delivers the events by type to their subscribers, in subscription
+// order: at once (Publish) or when flushed at the end of the tick (Post).
+type EventBus struct {
+	handlers map[reflect.Type][]*handler
+	queue    []any
+	nextID   int
+}
+
+type handler struct {
+	id      int
+	fn      func(any)
+	removed bool
+}
+
+// Subscription is returned by Subscribe to unsubscribe
+type Subscription struct {
+	bus *EventBus
+	typ reflect.Type
+	id  int
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: map[reflect.Type][]*handler{}}
+}
+
+// Subscribe calls fn with every event of type E
+func Subscribe[E any](b *EventBus, fn func(E)) Subscription {
+	typ := reflect.TypeOf((*E)(nil)).Elem()
+	b.nextID += 1
+	b.handlers[typ] = append(b.handlers[typ], &handler{id: b.nextID, fn: func(e any) { fn(e.(E)) }})
+	return Subscription{bus: b, typ: typ, id: b.nextID}
+}
+
+// Unsubscribe stops the delivery, even of an event being delivered
+func (s Subscription) Unsubscribe() {
+	if s.bus == nil {
+		return
+	}
+	handlers := s.bus.handlers[s.typ]
+	for i, h := range handlers {
+		if h.id == s.id {
+			h.removed = true
+			s.bus.handlers[s.typ] = append(handlers[:i:i], handlers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Publish delivers the event now
+func (b *EventBus) Publish(e any) {
+	// a copy: the handlers may subscribe and unsubscribe
+	handlers := append([]*handler{}, b.handlers[reflect.TypeOf(e)]...)
+	for _, h := range handlers {
+		if !h.removed {
+			h.fn(e)
+		}
+	}
+}
+
+// Post queues the event until Flush
+func (b *EventBus) Post(e any) {
+	b.queue = append(b.queue, e)
+}
+
+// Flush delivers the queued events, in posting order, including the ones posted
+// while flushing. The game flushes at the end of each tick.
+func (b *EventBus) Flush() {
+	for len(b.queue) > 0 {
+		queue := b.queue
+		b.queue = nil
+		for _, e := range queue {
+			b.Publish(e)
+		}
+	}
+}
@@ -0,0 +1,472 @@
+package sim
+
+import (
+	"container/heap"
+	"math"
+)
+
+// ------------------------------------------------
+// Move is how a path step is reached from the previous one
+type Move int
+
+const (
+	Move_Start Move = iota // the first step, where the path starts
+	Move_Fly
+	Move_Walk
+	Move_Jump
+	Move_Fall
+)
+
+func (m Move) String() string {
+	return [...]string{"Start", "Fly", "Walk", "Jump", "Fall"}[m]
+}
+
+// PathStep is a cell of a path and the move reaching it
+type PathStep struct {
+	Cell Vec2D[int]
+	Move Move
+}
+
+// PathAgent is the size and the moves of a walking agent, in cells
+type PathAgent struct {
+	Height       int // cells above the standing cell the agent needs free
+	JumpHeight   int // 0: no jumps
+	JumpDistance int
+	MaxFall      int
+}
+
+// ----------------------------------------------------------------------------- Pathfinder struct
+// Pathfinder finds paths over the collision grid: A* on the free cells for
+// flyers (FlyPath), A* on a graph of the cells walking agents can stand on,
+// linked by walk, jump and fall moves, for the others (WalkPath).
+// The world blocks the cells of the platforms as they move and fall: the walking
+// graphs are updated around the cells that change, and the cached paths going by
+// them are dropped. The paths next to a cell that opened stay cached, still
+// valid if no longer the shortest.
+type Pathfinder struct {
+	Grid    *CollisionGrid
+	blocked map[Vec2D[int]]int // dynamic blocks on top of the grid, by the number of blockers
+	cache   map[pathKey][]PathStep
+	graphs  map[PathAgent]map[Vec2D[int]][]pathEdge
+}
+
+// most paths kept in the cache, it is emptied when it is full
+const PathCacheSize = 256
+
+type pathKey struct {
+	from, to Vec2D[int]
+	diagonal bool
+	agent    PathAgent // zero for flyers
+}
+
+type pathEdge struct {
+	to   Vec2D[int]
+	move Move
+	cost float64
+}
+
+func NewPathfinder(grid *CollisionGrid) *Pathfinder {
+	pf := &Pathfinder{Grid: grid, blocked: map[Vec2D[int]]int{}}
+	pf.Invalidate()
+	return pf
+}
+
+// Invalidate drops the cached paths and walking graphs
+func (pf *Pathfinder) Invalidate() {
+	pf.cache = map[pathKey][]PathStep{}
+	pf.graphs = map[PathAgent]map[Vec2D[int]][]pathEdge{}
+}
+
+// store caches the path, dropping the cached ones when there are too many
+func (pf *Pathfinder) store(key pathKey, path []PathStep) {
+	if len(pf.cache) >= PathCacheSize {
+		clear(pf.cache)
+	}
+	pf.cache[key] = path
+}
+
+// Block marks the cells as solid, e.g. the cells of a platform; the cells
+// blocked several times stay blocked until they are unblocked as many times
+func (pf *Pathfinder) Block(cells ...Vec2D[int]) {
+	pf.Reblock(nil, cells)
+}
+
+// Unblock gives the cells back to the grid
+func (pf *Pathfinder) Unblock(cells ...Vec2D[int]) {
+	pf.Reblock(cells, nil)
+}
+
+// Reblock unblocks the old cells and blocks the new ones, e.g. the cells of a
+// platform that moved, updating the graphs and the cache once
+func (pf *Pathfinder) Reblock(old, cells []Vec2D[int]) {
+	changed := []Vec2D[int]{}
+	for _, c := range cells {
+		if pf.blocked[c] == 0 {
+			changed = append(changed, c)
+		}
+		pf.blocked[c] += 1
+	}
+	for _, c := range old {
+		switch n := pf.blocked[c]; {
+		case n > 1:
+			pf.blocked[c] -= 1
+		case n == 1:
+			delete(pf.blocked, c)
+			changed = append(changed, c)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+	for key, path := range pf.cache {
+		if path == nil || pathTouches(path, key.agent, changed) {
+			delete(pf.cache, key)
+		}
+	}
+	for agent, graph := range pf.graphs {
+		pf.updateGraph(graph, agent, changed)
+	}
+}
+
+// pathTouches reports whether one of the cells is on the path, around the moves
+// of the agent: the cells between two steps, the agent height (and a jump)
+// above them and the ground below
+func pathTouches(path []PathStep, agent PathAgent, cells []Vec2D[int]) bool {
+	up := max(agent.Height, 1) + agent.JumpHeight
+	for i, step := range path {
+		a, b := path[max(i-1, 0)].Cell, step.Cell
+		lo := Vec2D[int]{min(a.X, b.X), min(a.Y, b.Y) - up}
+		hi := Vec2D[int]{max(a.X, b.X), max(a.Y, b.Y) + 1}
+		for _, c := range cells {
+			if c.X >= lo.X && c.X <= hi.X && c.Y >= lo.Y && c.Y <= hi.Y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Blocked reports whether the cell is blocked
+func (pf *Pathfinder) Blocked(c Vec2D[int]) bool {
+	return pf.blocked[c] > 0
+}
+
+// CellsOf returns the cells the box covers
+func (pf *Pathfinder) CellsOf(r Rect[float64]) []Vec2D[int] {
+	cells := []Vec2D[int]{}
+	if r.Empty() {
+		return cells
+	}
+	lo := pf.CellOf(r.Pos())
+	hi := pf.CellOf(Vec2D[float64]{r.Right() - collisionEps, r.Bottom() - collisionEps})
+	for y := lo.Y; y <= hi.Y; y++ {
+		for x := lo.X; x <= hi.X; x++ {
+			cells = append(cells, Vec2D[int]{x, y})
+		}
+	}
+	return cells
+}
+
+// CellOf returns the cell of the world position
+func (pf *Pathfinder) CellOf(p Vec2D[float64]) Vec2D[int] {
+	return Vec2D[int]{pf.Grid.ToCell(p.X), pf.Grid.ToCell(p.Y)}
+}
+
+// CellCenter returns the world position of the center of the cell
+func (pf *Pathfinder) CellCenter(c Vec2D[int]) Vec2D[float64] {
+	cs := float64(pf.Grid.CellSize)
+	return c.Float().Scale(cs).AddDelta(cs / 2)
+}
+
+// free reports whether an agent can be in the cell: inside the level, not
+// solid, not blocked, and not a hazard
+func (pf *Pathfinder) free(c Vec2D[int]) bool {
+	if !pf.Grid.inside(c.X, c.Y) || pf.Blocked(c) {
+		return false
+	}
+	cell := pf.Grid.Cell(c.X, c.Y)
+	return !cell.IsSolid() && cell != CellHazard
+}
+
+// freeColumn reports whether the h cells from c upwards are free
+func (pf *Pathfinder) freeColumn(c Vec2D[int], h int) bool {
+	for i := 0; i < h; i++ {
+		if !pf.free(Vec2D[int]{c.X, c.Y - i}) {
+			return false
+		}
+	}
+	return true
+}
+
+// ----------------------------------------------------------------------------- flyers
+var (
+	dirs4 = []Vec2D[int]{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	dirs8 = []Vec2D[int]{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {-1, 1}, {1, -1}, {-1, -1}}
+)
+
+// FlyPath returns the path of free cells from one cell to the other, moving
+// in 4 directions or also diagonally (without cutting corners); nil when there
+// is none. The first step is the start cell.
+func (pf *Pathfinder) FlyPath(from, to Vec2D[int], diagonal bool) []PathStep {
+	key := pathKey{from: from, to: to, diagonal: diagonal}
+	if path, ok := pf.cache[key]; ok {
+		return path
+	}
+	dirs := dirs4
+	if diagonal {
+		dirs = dirs8
+	}
+	path := pf.astar(from, to, flyHeuristic, func(c Vec2D[int]) []pathEdge {
+		edges := []pathEdge{}
+		for _, d := range dirs {
+			n := c.Add(d)
+			if !pf.free(n) {
+				continue
+			}
+			cost := 1.
+			if d.X != 0 && d.Y != 0 {
+				if !pf.free(Vec2D[int]{n.X, c.Y}) || !pf.free(Vec2D[int]{c.X, n.Y}) {
+					continue
+				}
+				cost = math.Sqrt2
+			}
+			edges = append(edges, pathEdge{n, Move_Fly, cost})
+		}
+		return edges
+	})
+	pf.store(key, path)
+	return path
+}
+
+// ----------------------------------------------------------------------------- walkers
+// Standable reports whether an agent can stand in the cell: free for its
+// height, with the ground (solid cell, platform) below or on a slope.
+func (pf *Pathfinder) Standable(c Vec2D[int], agent PathAgent) bool {
+	if !pf.freeColumn(c, max(agent.Height, 1)) {
+		return false
+	}
+	if pf.Grid.Cell(c.X, c.Y).IsSlope() {
+		return true
+	}
+	below := Vec2D[int]{c.X, c.Y + 1}
+	return pf.Blocked(below) || pf.Grid.IsSolid(below.X, below.Y) || pf.Grid.IsPlatform(below.X, below.Y) ||
+		pf.Grid.Cell(below.X, below.Y).IsSlope()
+}
+
+// WalkPath returns the path from one standing cell to the other using the
+// walk, jump and fall moves of the agent; nil when there is none.
+func (pf *Pathfinder) WalkPath(from, to Vec2D[int], agent PathAgent) []PathStep {
+	key := pathKey{from: from, to: to, agent: agent}
+	if path, ok := pf.cache[key]; ok {
+		return path
+	}
+	graph := pf.walkGraph(agent)
+	path := pf.astar(from, to, walkHeuristic, func(c Vec2D[int]) []pathEdge {
+		return graph[c]
+	})
+	pf.store(key, path)
+	return path
+}
+
+// walkGraph links the standing cells of the whole level (built once per agent)
+func (pf *Pathfinder) walkGraph(agent PathAgent) map[Vec2D[int]][]pathEdge {
+	if graph, ok := pf.graphs[agent]; ok {
+		return graph
+	}
+	graph := map[Vec2D[int]][]pathEdge{}
+	g := pf.Grid
+	for cy := 0; cy < g.Height; cy++ {
+		for cx := 0; cx < g.Width; cx++ {
+			c := Vec2D[int]{cx, cy}
+			if pf.Standable(c, agent) {
+				graph[c] = pf.walkEdges(c, agent)
+			}
+		}
+	}
+	pf.graphs[agent] = graph
+	return graph
+}
+
+// updateGraph relinks the standing cells whose moves can go through the
+// changed cells: up to a jump distance on the sides, from a fall (or a jump)
+// above to a jump and the agent height below
+func (pf *Pathfinder) updateGraph(graph map[Vec2D[int]][]pathEdge, agent PathAgent, changed []Vec2D[int]) {
+	h := max(agent.Height, 1)
+	side := max(agent.JumpDistance, 1)
+	above := max(agent.MaxFall, agent.JumpHeight) + 1
+	done := map[Vec2D[int]]bool{}
+	for _, c := range changed {
+		for y := c.Y - above; y <= c.Y+agent.JumpHeight+h; y++ {
+			for x := c.X - side; x <= c.X+side; x++ {
+				n := Vec2D[int]{x, y}
+				if done[n] || !pf.Grid.inside(x, y) {
+					continue
+				}
+				done[n] = true
+				if pf.Standable(n, agent) {
+					graph[n] = pf.walkEdges(n, agent)
+				} else {
+					delete(graph, n)
+				}
+			}
+		}
+	}
+}
+
+func (pf *Pathfinder) walkEdges(c Vec2D[int], agent PathAgent) []pathEdge {
+	edges := []pathEdge{}
+	h := max(agent.Height, 1)
+	for _, side := range []int{1, -1} {
+		// walk, up and down slopes too
+		for _, dy := range []int{0, -1, 1} {
+			n := Vec2D[int]{c.X + side, c.Y + dy}
+			if dy != 0 && !pf.Grid.Cell(n.X, n.Y).IsSlope() && !pf.Grid.Cell(c.X, c.Y).IsSlope() {
+				continue
+			}
+			if pf.Standable(n, agent) && pf.freeColumn(Vec2D[int]{n.X, min(c.Y, n.Y)}, h) {
+				edges = append(edges, pathEdge{n, Move_Walk, 1})
+				break
+			}
+		}
+		// fall off the ledge
+		n := Vec2D[int]{c.X + side, c.Y}
+		if pf.freeColumn(n, h) && !pf.Standable(n, agent) {
+			for dy := 1; dy <= agent.MaxFall; dy++ {
+				d := Vec2D[int]{n.X, n.Y + dy}
+				if !pf.free(d) {
+					break
+				}
+				if pf.Standable(d, agent) {
+					edges = append(edges, pathEdge{d, Move_Fall, 1 + float64(dy)/2})
+					break
+				}
+			}
+		}
+		// jumps, over gaps and onto ledges
+		for dx := 1; dx <= agent.JumpDistance; dx++ {
+			for dy := -agent.JumpHeight; dy <= agent.JumpHeight; dy++ {
+				n := Vec2D[int]{c.X + side*dx, c.Y + dy}
+				if (dx == 1 && dy >= 0) || !pf.Standable(n, agent) || !pf.jumpClear(c, n, agent) {
+					continue
+				}
+				edges = append(edges, pathEdge{n, Move_Jump, 2 + float64(dx) + math.Abs(float64(dy))})
+			}
+		}
+	}
+	return edges
+}
+
+// jumpClear checks the jump arc as boxes: up from the start to the apex (the
+// feet one cell above the highest end), across, and down to the end
+func (pf *Pathfinder) jumpClear(from, to Vec2D[int], agent PathAgent) bool {
+	apex := min(from.Y, to.Y) - 1
+	if from.Y-apex > agent.JumpHeight {
+		return false
+	}
+	h := max(agent.Height, 1)
+	step := 1
+	if to.X < from.X {
+		step = -1
+	}
+	for x := from.X; x != to.X+step; x += step {
+		bottom := apex
+		switch x {
+		case from.X:
+			bottom = from.Y
+		case to.X:
+			bottom = to.Y
+		}
+		for y := apex - h + 1; y <= bottom; y++ {
+			if !pf.free(Vec2D[int]{x, y}) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ----------------------------------------------------------------------------- A*
+func (pf *Pathfinder) astar(from, to Vec2D[int], heuristic func(a, b Vec2D[int]) float64, neighbors func(Vec2D[int]) []pathEdge) []PathStep {
+	type node struct {
+		cost float64
+		prev Vec2D[int]
+		move Move
+	}
+	nodes := map[Vec2D[int]]*node{from: {move: Move_Start}}
+	open := &pathQueue{}
+	seq := 0
+	heap.Push(open, pathItem{from, heuristic(from, to), seq})
+	closed := map[Vec2D[int]]bool{}
+
+	for open.Len() > 0 {
+		c := heap.Pop(open).(pathItem).cell
+		if c == to {
+			path := []PathStep{}
+			for {
+				n := nodes[c]
+				path = append(path, PathStep{c, n.move})
+				if c == from {
+					break
+				}
+				c = n.prev
+			}
+			// from start to end
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+		if closed[c] {
+			continue
+		}
+		closed[c] = true
+		for _, e := range neighbors(c) {
+			cost := nodes[c].cost + e.cost
+			if n, ok := nodes[e.to]; ok && n.cost <= cost {
+				continue
+			}
+			nodes[e.to] = &node{cost, c, e.move}
+			seq += 1
+			heap.Push(open, pathItem{e.to, cost + heuristic(e.to, to), seq})
+		}
+	}
+	return nil
+}
+
+// octile distance: exact without obstacles for the 8 direction moves (straight
+// 1, diagonal √2), under the Manhattan distance of the 4 direction ones, so
+// admissible but not exact there
+func flyHeuristic(a, b Vec2D[int]) float64 {
+	dx, dy := math.Abs(float64(a.X-b.X)), math.Abs(float64(a.Y-b.Y))
+	return math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy)
+}
+
+// horizontal distance: every walking move costs at least the columns it crosses
+func walkHeuristic(a, b Vec2D[int]) float64 {
+	return math.Abs(float64(a.X - b.X))
+}
+
+// priority queue of the A* open cells, lowest estimate first, then first pushed
+type pathItem struct {
+	cell Vec2D[int]
+	f    float64
+	seq  int
+}
+
+type pathQueue []pathItem
+
+func (q pathQueue) Len() int { return len(q) }
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].f != q[j].f {
+		return q[i].f < q[j].f
+	}
+	return q[i].seq < q[j].seq
+}
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathItem)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
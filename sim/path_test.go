@@ -0,0 +1,126 @@
+package sim
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func TestPathfinderBlock(t *testing.T) {
+	pf := NewPathfinder(testGrid(
+		".....",
+		".....",
+		".....",
+	))
+	from, to := Vec2D[int]{0, 1}, Vec2D[int]{4, 1}
+	if path := pf.FlyPath(from, to, false); len(path) != 5 {
+		t.Fatalf("open path of %d steps, want 5", len(path))
+	}
+	wall := []Vec2D[int]{{2, 0}, {2, 1}, {2, 2}}
+	pf.Block(wall...)
+	pf.Block(wall[1]) // a second blocker
+	if path := pf.FlyPath(from, to, false); path != nil {
+		t.Fatalf("path through the blocked cells: %v", path)
+	}
+	pf.Unblock(wall...)
+	if !pf.Blocked(wall[1]) || pf.Blocked(wall[0]) {
+		t.Errorf("unblocking once: blocked %v %v, want false true", pf.Blocked(wall[0]), pf.Blocked(wall[1]))
+	}
+	if path := pf.FlyPath(from, to, false); len(path) != 7 {
+		t.Errorf("path around the blocked cell of %d steps, want 7", len(path))
+	}
+	pf.Unblock(wall[1])
+	if path := pf.FlyPath(from, to, false); len(path) != 5 {
+		t.Errorf("unblocked path of %d steps, want 5", len(path))
+	}
+}
+
+func TestPathfinderReblock(t *testing.T) {
+	grid := testGrid(
+		"....................",
+		"....................",
+		"....................",
+		"..........####......",
+		"....................",
+		"....................",
+		"####################",
+	)
+	agent := PathAgent{Height: 1, JumpHeight: 3, JumpDistance: 3, MaxFall: 4}
+	pf := NewPathfinder(grid)
+	near := pathKey{from: Vec2D[int]{0, 5}, to: Vec2D[int]{8, 5}, agent: agent}
+	far := pathKey{from: Vec2D[int]{14, 5}, to: Vec2D[int]{19, 5}, agent: agent}
+	pf.WalkPath(near.from, near.to, agent)
+	pf.WalkPath(far.from, far.to, agent)
+
+	// a platform moving right under the ledge, then gone
+	cells := []Vec2D[int]{}
+	for x := 0; x <= 8; x++ {
+		moved := []Vec2D[int]{{x, 4}, {x + 1, 4}}
+		if x == 8 {
+			moved = nil
+		}
+		pf.Reblock(cells, moved)
+		cells = moved
+
+		fresh := NewPathfinder(grid)
+		fresh.blocked = maps.Clone(pf.blocked)
+		if !reflect.DeepEqual(pf.graphs[agent], fresh.walkGraph(agent)) {
+			t.Fatalf("platform at %d: the updated graph differs from a new one", x)
+		}
+	}
+	if _, ok := pf.cache[near]; ok {
+		t.Error("path under the platform still cached")
+	}
+	if _, ok := pf.cache[far]; !ok {
+		t.Error("path away from the platform dropped")
+	}
+}
+
+func TestPlatformBlocksPaths(t *testing.T) {
+	pf := NewPathfinder(testGrid(
+		"......",
+		"......",
+		"......",
+		"......",
+	))
+	p := &Platform{
+		Solid: Solid{Rect: Rect[float64]{0, 16, 32, 16}, Active: true},
+		Path:  []Vec2D[float64]{{0, 16}, {64, 16}},
+		Speed: 4,
+		step:  1, target: 1,
+	}
+	p.block(pf)
+	if !pf.Blocked(Vec2D[int]{0, 1}) || !pf.Blocked(Vec2D[int]{1, 1}) || pf.Blocked(Vec2D[int]{2, 1}) {
+		t.Fatalf("platform cells not blocked: %v", p.cells)
+	}
+	for i := 0; i < 4; i++ {
+		p.Update(false, 64)
+		p.block(pf)
+	}
+	// moved by 16 pixels, one cell
+	if pf.Blocked(Vec2D[int]{0, 1}) || !pf.Blocked(Vec2D[int]{2, 1}) {
+		t.Errorf("moved platform cells %v", p.cells)
+	}
+	p.Solid.Active = false
+	p.block(pf)
+	if len(pf.blocked) != 0 {
+		t.Errorf("cells still blocked after the platform is gone: %v", pf.blocked)
+	}
+}
+
+func TestPathCacheBound(t *testing.T) {
+	pf := NewPathfinder(testGrid(
+		"................",
+		"................",
+		"................",
+		"................",
+	))
+	for i := 0; i < 2*PathCacheSize; i++ {
+		from := Vec2D[int]{i % 16, (i / 16) % 4}
+		to := Vec2D[int]{(i * 7) % 16, (i / 64) % 4}
+		pf.FlyPath(from, to, i%2 == 0)
+		if len(pf.cache) > PathCacheSize {
+			t.Fatalf("%d cached paths, more than %d", len(pf.cache), PathCacheSize)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package sim
+
+import "math"
+
+// PlayerPhysics is the movement tuning of a player character, loaded from the
+// character manifest and the config (see DefaultPlayerPhysics for the defaults).
+// Speeds are in pixels per tick, durations in ticks.
+type PlayerPhysics struct {
+	MoveSpeed float64 `json:"move_speed"`
+	// horizontal speed change per tick towards MoveSpeed (accel) or towards 0
+	// (decel, no direction pressed); TurnBoost multiplies the acceleration when
+	// pressing against the current velocity
+	GroundAccel float64 `json:"ground_accel"`
+	GroundDecel float64 `json:"ground_decel"`
+	AirAccel    float64 `json:"air_accel"`
+	AirDecel    float64 `json:"air_decel"`
+	TurnBoost   float64 `json:"turn_boost"`
+	// scale of the ground acceleration and deceleration on ice
+	IceFriction  float64 `json:"ice_friction"`
+	Gravity      float64 `json:"gravity"`
+	JumpSpeed    float64 `json:"jump_speed"`
+	MaxFallSpeed float64 `json:"max_fall_speed"` // kept below the grid cell size
+	// forgiveness: jumping a little after leaving a ledge, or pressing jump a
+	// little before landing
+	CoyoteTicks     int `json:"coyote_ticks"`
+	JumpBufferTicks int `json:"jump_buffer_ticks"`
+	// releasing jump while going up multiplies the upward speed by JumpCut
+	JumpCut float64 `json:"jump_cut"`
+	// gravity is scaled by ApexGravity near the top of a jump (|vy| < ApexThreshold)
+	// while jump is held
+	ApexThreshold float64 `json:"apex_threshold"`
+	ApexGravity   float64 `json:"apex_gravity"`
+	ClimbSpeed    float64 `json:"climb_speed"`
+	// movement in water
+	WaterGravity   float64 `json:"water_gravity"`
+	WaterMaxFall   float64 `json:"water_max_fall"`
+	WaterMoveScale float64 `json:"water_move_scale"`
+	WaterSwimSpeed float64 `json:"water_swim_speed"`
+}
+
+func DefaultPlayerPhysics() PlayerPhysics {
+	return PlayerPhysics{
+		MoveSpeed:       1.5,
+		GroundAccel:     0.25,
+		GroundDecel:     0.35,
+		AirAccel:        0.15,
+		AirDecel:        0.05,
+		TurnBoost:       2.0,
+		IceFriction:     0.15,
+		Gravity:         0.25,
+		JumpSpeed:       5.0,
+		MaxFallSpeed:    6.0,
+		CoyoteTicks:     6,
+		JumpBufferTicks: 6,
+		JumpCut:         0.5,
+		ApexThreshold:   0.8,
+		ApexGravity:     0.5,
+		ClimbSpeed:      1.0,
+		WaterGravity:    0.08,
+		WaterMaxFall:    1.5,
+		WaterMoveScale:  0.6,
+		WaterSwimSpeed:  2.0,
+	}
+}
+
+// maxFallSpeed keeps the fall speed below the cell size, or the player could
+// go through one cell thick floors
+func (pp *PlayerPhysics) maxFallSpeed(grid *CollisionGrid) float64 {
+	return min(pp.MaxFallSpeed, float64(grid.CellSize)-1)
+}
+
+// accel returns the horizontal speed change for this tick, dir being the
+// pressed direction (-1, 0 or 1)
+func (pp *PlayerPhysics) accel(dir, vx float64, grounded, onIce bool) float64 {
+	var a float64
+	switch {
+	case grounded && dir != 0:
+		a = pp.GroundAccel
+	case grounded:
+		a = pp.GroundDecel
+	case dir != 0:
+		a = pp.AirAccel
+	default:
+		a = pp.AirDecel
+	}
+	if dir*vx < 0 {
+		a *= pp.TurnBoost
+	}
+	if grounded && onIce {
+		a *= pp.IceFriction
+	}
+	return a
+}
+
+// approach moves v towards target by at most step
+func approach(v, target, step float64) float64 {
+	if v < target {
+		return math.Min(v+step, target)
+	}
+	return math.Max(v-step, target)
+}
@@ -0,0 +1,88 @@
+package sim
+
+import (
+	"fmt"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// Pickup and checkpoint entities identifiers; fields:
+//
+//	id     String, unique in the level, for the saves ("<identifier>_<x>_<y>" by default)
+//	kind   String or Enum, the fruit (Apple, Bananas, Cherries, Kiwi, Melon, Orange, Pineapple, Strawberry)
+//	value  Int, points given by a pickup
+const (
+	EntityFruit       = "Fruit"
+	EntityCollectible = "Collectible"
+	EntityCheckpoint  = "Checkpoint"
+)
+
+const (
+	PickupValue = 100
+	PickupKind  = "Apple"
+)
+
+// entityID returns the id field of the entity, or an id made from its position
+func entityID(e *ldtkgo.Entity, layer *ldtkgo.Layer) string {
+	if id := propString(e, "id", ""); id != "" {
+		return id
+	}
+	return fmt.Sprintf("%s_%d_%d", e.Identifier, e.Position[0]+layer.OffsetX, e.Position[1]+layer.OffsetY)
+}
+
+// ----------------------------------------------------------------------------- Pickup struct
+type Pickup struct {
+	Rect[float64]
+	ID          string
+	Kind        string
+	Value       int
+	Collected   bool
+	CollectedAt int64 // tick, for the collected effect (-1 when restored by World.Restore)
+}
+
+// NewPickup builds a pickup from its LDtk entity
+func NewPickup(e *ldtkgo.Entity, layer *ldtkgo.Layer) *Pickup {
+	x, y, w, h := entityBounds(e, layer)
+	return &Pickup{
+		Rect:  Rect[float64]{x, y, w, h},
+		ID:    entityID(e, layer),
+		Kind:  propString(e, "kind", PickupKind),
+		Value: propInt(e, "value", PickupValue),
+	}
+}
+
+// ----------------------------------------------------------------------------- Checkpoint struct
+// Checkpoint sets the respawn point of the player when touched
+type Checkpoint struct {
+	Rect[float64]
+	ID     string
+	Active bool
+}
+
+func NewCheckpoint(e *ldtkgo.Entity, layer *ldtkgo.Layer) *Checkpoint {
+	x, y, w, h := entityBounds(e, layer)
+	return &Checkpoint{
+		Rect: Rect[float64]{x, y, w, h},
+		ID:   entityID(e, layer),
+	}
+}
+
+// Spawn returns the player position standing on the bottom center of the checkpoint
+func (c *Checkpoint) Spawn() Vec2D[float64] {
+	return standingOn(c.Rect)
+}
+
+// NewItems builds the pickups and checkpoints of all the level entities
+func NewItems(level *ldtkgo.Level) ([]*Pickup, []*Checkpoint) {
+	pickups := []*Pickup{}
+	checkpoints := []*Checkpoint{}
+	entities(level, func(e *ldtkgo.Entity, layer *ldtkgo.Layer) {
+		switch e.Identifier {
+		case EntityFruit, EntityCollectible:
+			pickups = append(pickups, NewPickup(e, layer))
+		case EntityCheckpoint:
+			checkpoints = append(checkpoints, NewCheckpoint(e, layer))
+		}
+	})
+	return pickups, checkpoints
+}
@@ -0,0 +1,226 @@
+package sim
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// ----------------------------------------------------------------------------- Solid struct
+// Solid is a moving box of the dynamic collision geometry, alongside the static
+// CollisionGrid. Dx, Dy is its motion in the current tick, carried over to what
+// stands on it.
+type Solid struct {
+	Rect[float64]
+	OneWay bool // solid only from above
+	Dx, Dy float64
+	Active bool
+}
+
+func (s *Solid) Bounds() Rect[float64] {
+	return s.Rect
+}
+
+func (s *Solid) overlaps(r Rect[float64]) bool {
+	return s.Active && s.Intersects(r)
+}
+
+// ------------------------------------------------
+type PlatformMode int
+
+const (
+	PlatformPingPong PlatformMode = iota // back and forth along the path
+	PlatformLoop                         // from the last point back to the first
+)
+
+func (m PlatformMode) String() string {
+	return [...]string{"PingPong", "Loop"}[m]
+}
+
+// Platform entities identifiers; fields:
+//
+//	path       Point array, the points the platform travels through (starting from its position)
+//	speed      Float, pixels per tick
+//	mode       String or Enum, "PingPong" or "Loop"
+//	ease       String, easing from point to point ("QuadInOut", "BackOut"... see Easings), constant speed if empty
+//	solid      Bool, solid from every side (one-way from above by default)
+//	falls      Bool, falls after being stood on (always true for FallingPlatform)
+//	fall_delay Int, ticks stood on before falling
+const (
+	EntityPlatform        = "Platform"
+	EntityMovingPlatform  = "MovingPlatform"
+	EntityFallingPlatform = "FallingPlatform"
+)
+
+const (
+	PlatformSpeed     = 1.0
+	PlatformFallDelay = 30
+	PlatformGravity   = 0.2
+	PlatformMaxFall   = 6.0
+)
+
+// ----------------------------------------------------------------------------- Platform struct
+type Platform struct {
+	Solid     Solid
+	Path      []Vec2D[float64] // top-left positions
+	Speed     float64
+	Mode      PlatformMode
+	Ease      Easing // nil: constant speed
+	Falls     bool
+	FallDelay int
+	// state
+	target    int
+	step      int            // +1 / -1 along the path
+	from      Vec2D[float64] // eased: start of the segment to the target
+	segTick   int
+	stoodOn   int // ticks
+	Falling   bool
+	fallSpeed float64
+	cells     []Vec2D[int] // blocked in the pathfinder
+}
+
+// NewPlatform builds a platform from its LDtk entity
+func NewPlatform(e *ldtkgo.Entity, layer *ldtkgo.Layer) *Platform {
+	x, y, w, h := entityBounds(e, layer)
+	p := &Platform{
+		Solid:     Solid{Rect: Rect[float64]{x, y, w, h}, OneWay: !propBool(e, "solid", false), Active: true},
+		Speed:     propFloat(e, "speed", PlatformSpeed),
+		Falls:     propBool(e, "falls", e.Identifier == EntityFallingPlatform),
+		FallDelay: propInt(e, "fall_delay", PlatformFallDelay),
+		step:      1,
+	}
+	if strings.EqualFold(propString(e, "mode", ""), PlatformLoop.String()) {
+		p.Mode = PlatformLoop
+	}
+	if name := propString(e, "ease", ""); name != "" {
+		if ease, ok := EasingByName(name); ok {
+			p.Ease = ease
+		}
+	}
+	p.from = Vec2D[float64]{x, y}
+	p.Path = append([]Vec2D[float64]{{x, y}}, propPoints(e, "path", layer)...)
+	if len(p.Path) > 1 {
+		p.target = 1
+	}
+	return p
+}
+
+// NewPlatforms builds the platforms of all the level platform entities
+func NewPlatforms(level *ldtkgo.Level) []*Platform {
+	platforms := []*Platform{}
+	entities(level, func(e *ldtkgo.Entity, layer *ldtkgo.Layer) {
+		switch e.Identifier {
+		case EntityPlatform, EntityMovingPlatform, EntityFallingPlatform:
+			platforms = append(platforms, NewPlatform(e, layer))
+		}
+	})
+	return platforms
+}
+
+// Update moves the platform; standing tells whether something stands on it.
+// Platforms falling below the level bottom are deactivated.
+func (p *Platform) Update(standing bool, levelHeight float64) {
+	s := &p.Solid
+	s.Dx, s.Dy = 0, 0
+	if !s.Active {
+		return
+	}
+
+	if p.Falling {
+		p.fallSpeed = math.Min(p.fallSpeed+PlatformGravity, PlatformMaxFall)
+		s.Dy = p.fallSpeed
+		s.Y += s.Dy
+		if s.Y > levelHeight {
+			s.Active = false
+		}
+		return
+	}
+	if p.Falls && standing {
+		p.stoodOn += 1
+		if p.stoodOn >= p.FallDelay {
+			p.Falling = true
+		}
+	}
+
+	if len(p.Path) < 2 || p.Speed <= 0 {
+		return
+	}
+	if p.Ease != nil {
+		p.moveEased()
+		return
+	}
+	// move towards the target point, going on to the next ones with what is left
+	left := p.Speed
+	for left > 0 {
+		t := p.Path[p.target]
+		d := t.Sub(s.Pos())
+		dist := d.Length()
+		if dist > left {
+			d = d.Normalize().Scale(left)
+			s.X += d.X
+			s.Y += d.Y
+			s.Dx += d.X
+			s.Dy += d.Y
+			break
+		}
+		s.X, s.Y = t.X, t.Y
+		s.Dx += d.X
+		s.Dy += d.Y
+		left -= dist
+		p.nextTarget()
+		if dist == 0 && left == p.Speed {
+			break // all the path points are the same
+		}
+	}
+}
+
+// block blocks the cells of the platform in the pathfinder, when they change
+// (the platform moved or fell) or when it is gone
+func (p *Platform) block(paths *Pathfinder) {
+	cells := []Vec2D[int]{}
+	if p.Solid.Active {
+		cells = paths.CellsOf(p.Solid.Rect)
+	}
+	if slices.Equal(cells, p.cells) {
+		return
+	}
+	paths.Reblock(p.cells, cells)
+	p.cells = cells
+}
+
+// unblock gives the cells of the platform back to the pathfinder
+func (p *Platform) unblock(paths *Pathfinder) {
+	paths.Unblock(p.cells...)
+	p.cells = nil
+}
+
+// moveEased moves from point to point, easing over the time the segment takes
+// at the platform speed
+func (p *Platform) moveEased() {
+	s := &p.Solid
+	t := p.Path[p.target]
+	ticks := max(1, int(math.Ceil(t.Sub(p.from).Length()/p.Speed)))
+	p.segTick += 1
+	pos := p.from.Lerp(t, p.Ease(float64(p.segTick)/float64(ticks)))
+	s.Dx, s.Dy = pos.Sub(s.Pos()).XY()
+	s.X, s.Y = pos.XY()
+	if p.segTick >= ticks {
+		p.from = t
+		p.segTick = 0
+		p.nextTarget()
+	}
+}
+
+func (p *Platform) nextTarget() {
+	n := len(p.Path)
+	if p.Mode == PlatformLoop {
+		p.target = (p.target + 1) % n
+		return
+	}
+	if p.target+p.step < 0 || p.target+p.step >= n {
+		p.step = -p.step
+	}
+	p.target += p.step
+}
@@ -0,0 +1,491 @@
+package sim
+
+import "math"
+
+type Dir int
+
+const (
+	Dir_Right Dir = iota
+	Dir_Left
+)
+
+// ------------------------------------------------
+type PlayerState int
+
+const (
+	Player_Idle PlayerState = iota
+	Player_Run
+	Player_Jump
+	Player_Climb
+	Player_Hit
+	Player_Dead
+)
+
+// String - Creating common behavior - give the type a String function
+func (d PlayerState) String() string {
+	return [...]string{"Idle", "Run", "Jump", "Climb", "Hit", "Dead"}[d]
+}
+
+// EnumIndex - Creating common behavior - give the type a EnumIndex functio
+func (d PlayerState) EnumIndex() int {
+	return int(d)
+}
+
+// ----------------------------------------------
+// the movement tuning is in PlayerPhysics
+const (
+	SpawnX    = 50.0
+	SpawnY    = 100.0
+	MaxHealth = 3
+	// ticks ignoring platforms after Down on a one-way platform
+	DropThroughTicks = 12
+	// ticks of invulnerability after being hurt, and the bounce on hazards
+	HurtTicks       = 60
+	HazardKnockback = 3.5
+	// ticks without control after being hurt, and the push away from enemies
+	HitStunTicks  = 20
+	EnemyPushback = 2.5
+	// the hop of the player when it dies, before falling off the screen
+	DeathHop = 4.0
+	// how far the hitbox bottom may sink into a solid cell on a slope before
+	// it blocks horizontally (steps onto the plateau at the top of a slope):
+	// more than half the hitbox width, plus the sinking when running up
+	SlopeStep = 12.0
+)
+
+// Hitbox of the player relative to its position (top-left of the 32x32 sprite frame)
+const (
+	HitboxX = 7
+	HitboxY = 6
+	HitboxW = 18
+	HitboxH = 26
+)
+
+// Player is the simulation state of the player: no images, no animations.
+type Player struct {
+	Pos          Vec2D[float64] // top-left of the sprite frame
+	Velocity     Vec2D[float64]
+	Dir          Dir
+	State        PlayerState
+	Grounded     bool
+	Climbing     bool
+	InWater      bool
+	Health       int
+	Invulnerable int // ticks left
+	OnSlope      bool
+	OnIce        bool
+	Riding       *Solid // the platform the player stands on
+	Jumped       bool   // jumped this tick
+	AgainstWall  bool   // walked into a wall or a solid this tick
+	WallSliding  bool   // falling along the wall it pushes against
+	Physics      PlayerPhysics
+	dropTimer    int
+	coyote       int  // ticks left to jump after leaving the ground
+	jumpBuffer   int  // ticks left to jump after pressing jump in the air
+	jumpHeld     bool // jump pressed in the previous tick
+	jumping      bool // going up from a jump, which can still be cut
+}
+
+func NewPlayer() *Player {
+	return &Player{
+		Pos:      Vec2D[float64]{SpawnX, SpawnY},
+		Velocity: Vec2D[float64]{0., 0.},
+		Dir:      Dir_Right,
+		State:    Player_Idle,
+		Health:   MaxHealth,
+		Physics:  DefaultPlayerPhysics(),
+	}
+}
+
+// Bounds returns the player hitbox in world coordinates
+func (p *Player) Bounds() Rect[float64] {
+	return Rect[float64]{p.Pos.X + HitboxX, p.Pos.Y + HitboxY, HitboxW, HitboxH}
+}
+
+// Dead reports whether the player has no health left
+func (p *Player) Dead() bool {
+	return p.Health <= 0
+}
+
+// Hurt takes one point of health, unless the player is still invulnerable
+func (p *Player) Hurt() {
+	if p.Invulnerable > 0 || p.Dead() {
+		return
+	}
+	p.Health -= 1
+	p.Invulnerable = HurtTicks
+	p.Climbing = false
+	p.State = Player_Hit
+	p.Velocity.Y = -HazardKnockback
+	if p.Dead() {
+		p.Kill()
+	}
+}
+
+// Kill kills the player at once (hazard damage, kill plane)
+func (p *Player) Kill() {
+	p.Health = 0
+	p.Invulnerable = 0
+	p.Climbing = false
+	p.Grounded = false
+	p.Riding = nil
+	p.State = Player_Dead
+	p.Velocity = Vec2D[float64]{0, -DeathHop}
+}
+
+// HurtFrom hurts the player and pushes it away from the world x
+func (p *Player) HurtFrom(from float64) {
+	if p.Invulnerable > 0 || p.Dead() {
+		return
+	}
+	p.Hurt()
+	p.Velocity.X = EnemyPushback
+	if from > p.Bounds().Center().X {
+		p.Velocity.X = -EnemyPushback
+	}
+}
+
+// Stunned reports whether the player was just hurt and has no control
+func (p *Player) Stunned() bool {
+	return p.Invulnerable > HurtTicks-HitStunTicks
+}
+
+// Bounce makes the player jump off what it stomped on, landing at the world y
+func (p *Player) Bounce(y float64) {
+	p.Pos.Y = y - HitboxH - HitboxY
+	p.Velocity.Y = -StompBounce
+	p.Grounded = false
+	p.jumping = true
+}
+
+// Update moves the player by one tick, colliding with the level grid and the
+// dynamic solids (already moved for this tick).
+func (p *Player) Update(in Input, grid *CollisionGrid, solids []*Solid) {
+	if p.Dead() {
+		// hop and fall through everything
+		p.Velocity.Y = math.Min(p.Velocity.Y+p.Physics.Gravity, p.Physics.MaxFallSpeed)
+		p.Pos = p.Pos.Add(p.Velocity)
+		p.State = Player_Dead
+		return
+	}
+	// carried by the platform it stands on
+	if p.Riding != nil && p.Riding.Active {
+		p.moveX(p.Riding.Dx, grid, solids)
+		p.Pos.Y += p.Riding.Dy
+	}
+
+	p.Jumped = false
+	jumpHeld := in.Jump
+	if p.Stunned() {
+		in = Input{}
+	}
+	dx := 0.
+	if in.Right {
+		dx += 1.
+	}
+	if in.Left {
+		dx -= 1.
+	}
+	if dx > 0 {
+		p.Dir = Dir_Right
+	} else if dx < 0 {
+		p.Dir = Dir_Left
+	}
+
+	pp := &p.Physics
+	p.updateClimbing(in, grid)
+	if p.dropTimer > 0 {
+		p.dropTimer -= 1
+	}
+
+	switch {
+	case p.Climbing:
+		p.Velocity.X = dx * pp.ClimbSpeed
+		p.Velocity.Y = 0
+		if in.Up {
+			p.Velocity.Y -= pp.ClimbSpeed
+		}
+		if in.Down {
+			p.Velocity.Y += pp.ClimbSpeed
+		}
+	case p.InWater:
+		p.Velocity.X = approach(p.Velocity.X, dx*pp.MoveSpeed*pp.WaterMoveScale, pp.accel(dx, p.Velocity.X, false, false))
+		if in.Jump {
+			p.Velocity.Y = -pp.WaterSwimSpeed
+		}
+		p.Velocity.Y = math.Min(p.Velocity.Y+pp.WaterGravity, pp.WaterMaxFall)
+	default:
+		p.Velocity.X = approach(p.Velocity.X, dx*pp.MoveSpeed, pp.accel(dx, p.Velocity.X, p.Grounded, p.OnIce))
+		p.updateJump(in)
+		if in.Down && p.Grounded && (p.onPlatform(grid) || (p.Riding != nil && p.Riding.OneWay)) {
+			p.dropTimer = DropThroughTicks
+		}
+		gravity := pp.Gravity
+		if !p.Grounded && in.Jump && math.Abs(p.Velocity.Y) < pp.ApexThreshold {
+			// hang a little at the top of the jump
+			gravity *= pp.ApexGravity
+		}
+		p.Velocity.Y = math.Min(p.Velocity.Y+gravity, pp.maxFallSpeed(grid))
+	}
+	p.jumpHeld = jumpHeld
+
+	wasGrounded := p.Grounded
+	p.AgainstWall = false
+	p.moveX(p.Velocity.X, grid, solids)
+	p.moveY(p.Velocity.Y, grid, solids)
+	if wasGrounded && !p.Grounded && p.Velocity.Y >= 0 && !p.Climbing {
+		p.snapDown(grid)
+	}
+	if p.Climbing && p.Grounded && in.Down {
+		// reached the bottom of the ladder
+		p.Climbing = false
+	}
+
+	p.WallSliding = p.AgainstWall && !p.Grounded && !p.Climbing && p.Velocity.Y > 0
+
+	x, y, w, h := p.Bounds().XYWH()
+	p.InWater = grid.Touches(x, y, w, h, CellWater)
+	p.OnIce = p.Grounded && grid.Touches(x, y+h, w, 1, CellIce)
+	if p.Invulnerable > 0 {
+		p.Invulnerable -= 1
+	} else if grid.Touches(x, y, w, h, CellHazard) {
+		p.Hurt()
+	}
+
+	switch {
+	case p.Stunned():
+		p.State = Player_Hit
+	case p.Climbing:
+		p.State = Player_Climb
+	case !p.Grounded:
+		p.State = Player_Jump
+	case p.Velocity.X != 0:
+		p.State = Player_Run
+	default:
+		p.State = Player_Idle
+	}
+}
+
+// jump with coyote time and input buffering, cut the jump when jump is released early
+func (p *Player) updateJump(in Input) {
+	pp := &p.Physics
+	if p.Grounded {
+		p.coyote = pp.CoyoteTicks
+	} else if p.coyote > 0 {
+		p.coyote -= 1
+	}
+	if in.Jump && !p.jumpHeld {
+		p.jumpBuffer = pp.JumpBufferTicks + 1
+	}
+	if p.jumpBuffer > 0 && (p.Grounded || p.coyote > 0) {
+		p.Velocity.Y = -pp.JumpSpeed
+		p.Jumped = true
+		p.jumpBuffer = 0
+		p.coyote = 0
+		p.jumping = true
+		return
+	}
+	if p.jumpBuffer > 0 {
+		p.jumpBuffer -= 1
+	}
+	if p.jumping && (p.Velocity.Y >= 0 || p.Grounded) {
+		p.jumping = false
+	}
+	if p.jumping && !in.Jump {
+		p.Velocity.Y *= pp.JumpCut
+		p.jumping = false
+	}
+}
+
+// grab a ladder with Up (or Down from its top), leave it by jumping or stepping off
+func (p *Player) updateClimbing(in Input, grid *CollisionGrid) {
+	x, y, w, h := p.Bounds().XYWH()
+	onLadder := grid.Touches(x, y, w, h, CellLadder)
+	cx := grid.ToCell(x + w/2)
+	ladderBelow := grid.Cell(cx, grid.ToCell(y+h)) == CellLadder
+
+	if !p.Climbing {
+		if (in.Up && grid.CellAt(x+w/2, y+h/2) == CellLadder) || (in.Down && p.Grounded && ladderBelow) {
+			p.Climbing = true
+			p.dropTimer = 0
+			// center on the ladder
+			p.Pos.X = float64(cx*grid.CellSize) + float64(grid.CellSize)/2 - w/2 - HitboxX
+		}
+		return
+	}
+	if in.Jump && !p.jumpHeld {
+		p.Climbing = false
+		p.Velocity.Y = -p.Physics.JumpSpeed
+		p.Jumped = true
+		p.jumping = true
+	} else if !onLadder {
+		p.Climbing = false
+	}
+}
+
+// is the player standing on a one-way platform (and not on solid ground)
+func (p *Player) onPlatform(grid *CollisionGrid) bool {
+	x, y, w, h := p.Bounds().XYWH()
+	cy := grid.ToCell(y + h)
+	platform := false
+	for cx := grid.ToCell(x); cx <= grid.ToCell(x+w-collisionEps); cx++ {
+		if grid.IsSolid(cx, cy) {
+			return false
+		}
+		if grid.IsPlatform(cx, cy) {
+			platform = true
+		}
+	}
+	return platform
+}
+
+// move horizontally and push the player out of the solid cell it walked into
+func (p *Player) moveX(dx float64, grid *CollisionGrid, solids []*Solid) {
+	p.Pos.X += dx
+	x, y, w, h := p.Bounds().XYWH()
+	for _, s := range solids {
+		if s.OneWay || !s.overlaps(p.Bounds()) {
+			continue
+		}
+		if dx > 0 {
+			p.Pos.X = s.X - w - HitboxX
+		} else if dx < 0 {
+			p.Pos.X = s.Right() - HitboxX
+		}
+		p.Velocity.X = 0
+		p.AgainstWall = dx != 0
+		x, y, w, h = p.Bounds().XYWH()
+	}
+	if p.OnSlope {
+		// raised on a slope: ground a few pixels above the feet is stepped on by moveY
+		h -= SlopeStep
+	}
+	if dx == 0 || !grid.Overlaps(x, y, w, h) {
+		return
+	}
+	cs := float64(grid.CellSize)
+	if dx > 0 {
+		p.Pos.X = float64(grid.ToCell(x+w))*cs - w - HitboxX
+	} else {
+		p.Pos.X = float64(grid.ToCell(x)+1)*cs - HitboxX
+	}
+	p.Velocity.X = 0
+	p.AgainstWall = true
+}
+
+// move vertically, landing on floors and platforms and bumping against ceilings
+func (p *Player) moveY(dy float64, grid *CollisionGrid, solids []*Solid) {
+	prevY := p.Bounds().Y
+	p.Pos.Y += dy
+	p.Grounded = false
+	p.OnSlope = false
+	p.Riding = nil
+	x, y, w, h := p.Bounds().XYWH()
+	if dy == 0 {
+		return
+	}
+	if p.collideSolids(prevY, solids) {
+		return
+	}
+	cs := float64(grid.CellSize)
+	if dy > 0 && !p.Climbing && p.landOnSlope(prevY+h, grid) {
+		return
+	}
+	if !grid.Overlaps(x, y, w, h) {
+		if dy < 0 || p.Climbing || p.dropTimer > 0 {
+			return
+		}
+		// platforms stop the player only when coming from above them
+		cy := grid.ToCell(y + h - collisionEps)
+		top := float64(cy) * cs
+		if prevY+h > top+collisionEps {
+			return
+		}
+		for cx := grid.ToCell(x); cx <= grid.ToCell(x+w-collisionEps); cx++ {
+			if grid.IsPlatform(cx, cy) {
+				p.Pos.Y = top - h - HitboxY
+				p.Grounded = true
+				p.Velocity.Y = 0
+				return
+			}
+		}
+		return
+	}
+	if dy > 0 {
+		p.Pos.Y = float64(grid.ToCell(y+h))*cs - h - HitboxY
+		p.Grounded = true
+	} else {
+		p.Pos.Y = float64(grid.ToCell(y)+1)*cs - HitboxY
+	}
+	p.Velocity.Y = 0
+}
+
+// land on the dynamic solids (and bump against the solid ones from below).
+// The lower of their previous and current tops is used, so rising platforms
+// pick up the player and falling ones keep it.
+func (p *Player) collideSolids(prevY float64, solids []*Solid) bool {
+	box := p.Bounds()
+	h := box.H
+	for _, s := range solids {
+		if !s.overlaps(box) {
+			continue
+		}
+		if p.Velocity.Y > 0 && !p.Climbing && prevY+h <= math.Max(s.Y, s.Y-s.Dy)+collisionEps && (!s.OneWay || p.dropTimer == 0) {
+			p.Pos.Y = s.Y - h - HitboxY
+			p.Grounded = true
+			p.Riding = s
+			p.Velocity.Y = 0
+			return true
+		}
+		if p.Velocity.Y < 0 && !s.OneWay && prevY >= math.Min(s.Y, s.Y-s.Dy)+s.H-collisionEps {
+			p.Pos.Y = s.Bottom() - HitboxY
+			p.Velocity.Y = 0
+			return true
+		}
+	}
+	return false
+}
+
+// slopes: only the bottom center stands on the surface, the solid cells under
+// the hitbox corners are ignored. Walking up a slope the feet sink below it by
+// up to the horizontal speed, even into the cell under the slope when stepping
+// on it from flat ground. It returns false when the player is not over a slope.
+func (p *Player) landOnSlope(prevBottom float64, grid *CollisionGrid) bool {
+	x, y, w, h := p.Bounds().XYWH()
+	cx, cy := grid.ToCell(x+w/2), grid.ToCell(y+h-collisionEps)
+	for _, row := range []int{cy, cy - 1} {
+		surface, ok := grid.SlopeSurface(cx, row, x+w/2)
+		if !ok {
+			continue
+		}
+		if y+h <= surface {
+			// above the slope: falling (or snapped down later)
+			return row == cy
+		}
+		if prevBottom > surface+math.Abs(p.Velocity.X)+collisionEps {
+			continue
+		}
+		p.Pos.Y = surface - h - HitboxY
+		p.Grounded = true
+		p.OnSlope = true
+		p.Velocity.Y = 0
+		return true
+	}
+	return false
+}
+
+// keep the player on the ground while running down a slope (or off its top)
+// instead of leaving it and falling in small hops
+func (p *Player) snapDown(grid *CollisionGrid) {
+	x, y, w, h := p.Bounds().XYWH()
+	maxDist := math.Abs(p.Velocity.X) + 1
+	floor, ok := grid.GroundBelow(x, y, w, h, maxDist)
+	if !ok {
+		return
+	}
+	p.Pos.Y += floor - (y + h)
+	p.Grounded = true
+	p.Velocity.Y = 0
+	cy := grid.ToCell(floor - collisionEps)
+	p.OnSlope = grid.Cell(grid.ToCell(x+w/2), cy).IsSlope()
+}
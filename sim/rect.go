@@ -0,0 +1,85 @@
+package sim
+
+// ----------------------------------------------------------------------------- Rect struct
+// Rect is an axis aligned box: top-left corner X, Y and size W, H. It contains
+// the points from X (included) to X+W (excluded), so two boxes sharing an edge
+// do not intersect.
+type Rect[T int | float64] struct {
+	X, Y, W, H T
+}
+
+func NewRect[T int | float64](pos, size Vec2D[T]) Rect[T] {
+	return Rect[T]{pos.X, pos.Y, size.X, size.Y}
+}
+
+func (r Rect[T]) Pos() Vec2D[T] {
+	return Vec2D[T]{r.X, r.Y}
+}
+
+func (r Rect[T]) Size() Vec2D[T] {
+	return Vec2D[T]{r.W, r.H}
+}
+
+func (r Rect[T]) Right() T {
+	return r.X + r.W
+}
+
+func (r Rect[T]) Bottom() T {
+	return r.Y + r.H
+}
+
+func (r Rect[T]) Center() Vec2D[float64] {
+	return Vec2D[float64]{float64(r.X) + float64(r.W)/2, float64(r.Y) + float64(r.H)/2}
+}
+
+func (r Rect[T]) Empty() bool {
+	return r.W <= 0 || r.H <= 0
+}
+
+// Grow returns the box grown by d on every side (shrunk when d < 0)
+func (r Rect[T]) Grow(d T) Rect[T] {
+	return Rect[T]{r.X - d, r.Y - d, r.W + 2*d, r.H + 2*d}
+}
+
+// Translate returns the box moved by d
+func (r Rect[T]) Translate(d Vec2D[T]) Rect[T] {
+	return Rect[T]{r.X + d.X, r.Y + d.Y, r.W, r.H}
+}
+
+func (r Rect[T]) Contains(p Vec2D[T]) bool {
+	return p.X >= r.X && p.X < r.Right() && p.Y >= r.Y && p.Y < r.Bottom()
+}
+
+func (r Rect[T]) Intersects(other Rect[T]) bool {
+	return r.X < other.Right() && r.Right() > other.X && r.Y < other.Bottom() && r.Bottom() > other.Y
+}
+
+// Overlap returns the intersection of the two boxes; false when they do not intersect
+func (r Rect[T]) Overlap(other Rect[T]) (Rect[T], bool) {
+	if !r.Intersects(other) {
+		return Rect[T]{}, false
+	}
+	x, y := max(r.X, other.X), max(r.Y, other.Y)
+	return Rect[T]{x, y, min(r.Right(), other.Right()) - x, min(r.Bottom(), other.Bottom()) - y}, true
+}
+
+// Union returns the smallest box containing both boxes
+func (r Rect[T]) Union(other Rect[T]) Rect[T] {
+	x, y := min(r.X, other.X), min(r.Y, other.Y)
+	return Rect[T]{x, y, max(r.Right(), other.Right()) - x, max(r.Bottom(), other.Bottom()) - y}
+}
+
+func (r Rect[T]) Float() Rect[float64] {
+	return Rect[float64]{float64(r.X), float64(r.Y), float64(r.W), float64(r.H)}
+}
+
+// Int returns the smallest int box containing the box
+func (r Rect[T]) Int() Rect[int] {
+	lo, hi := r.Pos().Int(), Vec2D[float64]{float64(r.Right()) - collisionEps, float64(r.Bottom()) - collisionEps}.Int()
+	return Rect[int]{lo.X, lo.Y, hi.X - lo.X + 1, hi.Y - lo.Y + 1}
+}
+
+// XYWH returns the box as the x, y, w, h the collision functions take
+func (r Rect[T]) XYWH() (T, T, T, T) {
+	return r.X, r.Y, r.W, r.H
+}
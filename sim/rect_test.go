@@ -0,0 +1,84 @@
+package sim
+
+import "testing"
+
+func TestRectIntersects(t *testing.T) {
+	r := Rect[float64]{0, 0, 10, 10}
+	tests := []struct {
+		name  string
+		other Rect[float64]
+		want  bool
+	}{
+		{"inside", Rect[float64]{2, 2, 4, 4}, true},
+		{"overlapping", Rect[float64]{5, 5, 10, 10}, true},
+		{"shared right edge", Rect[float64]{10, 0, 5, 10}, false},
+		{"shared bottom edge", Rect[float64]{0, 10, 10, 5}, false},
+		{"shared corner", Rect[float64]{10, 10, 5, 5}, false},
+		{"apart", Rect[float64]{20, 0, 5, 5}, false},
+	}
+	for _, tt := range tests {
+		if got := r.Intersects(tt.other); got != tt.want {
+			t.Errorf("%s: Intersects = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := tt.other.Intersects(r); got != tt.want {
+			t.Errorf("%s: reversed Intersects = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRectOverlap(t *testing.T) {
+	r := Rect[int]{0, 0, 10, 10}
+	tests := []struct {
+		other Rect[int]
+		want  Rect[int]
+		ok    bool
+	}{
+		{Rect[int]{5, 5, 10, 10}, Rect[int]{5, 5, 5, 5}, true},
+		{Rect[int]{-2, 3, 4, 2}, Rect[int]{0, 3, 2, 2}, true},
+		{Rect[int]{2, 2, 3, 3}, Rect[int]{2, 2, 3, 3}, true},
+		{Rect[int]{10, 0, 5, 5}, Rect[int]{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := r.Overlap(tt.other)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Overlap(%v) = %v, %v, want %v, %v", tt.other, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRectContains(t *testing.T) {
+	r := Rect[float64]{0, 0, 10, 10}
+	tests := []struct {
+		p    Vec2D[float64]
+		want bool
+	}{
+		{Vec2D[float64]{0, 0}, true},
+		{Vec2D[float64]{5, 9.99}, true},
+		{Vec2D[float64]{10, 5}, false},
+		{Vec2D[float64]{5, 10}, false},
+		{Vec2D[float64]{-0.1, 5}, false},
+	}
+	for _, tt := range tests {
+		if got := r.Contains(tt.p); got != tt.want {
+			t.Errorf("Contains(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestRectInt(t *testing.T) {
+	tests := []struct {
+		r    Rect[float64]
+		want Rect[int]
+	}{
+		{Rect[float64]{0, 0, 10, 10}, Rect[int]{0, 0, 10, 10}},
+		{Rect[float64]{0.5, 0.5, 2, 2}, Rect[int]{0, 0, 3, 3}},
+		{Rect[float64]{-0.5, -1.5, 1, 1}, Rect[int]{-1, -2, 2, 2}},
+		{Rect[float64]{-1, -2, 1, 1}, Rect[int]{-1, -2, 1, 1}},
+		{Rect[float64]{1.2, 3.7, 0.5, 0.2}, Rect[int]{1, 3, 1, 1}},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Int(); got != tt.want {
+			t.Errorf("%v.Int() = %v, want %v", tt.r, got, tt.want)
+		}
+	}
+}
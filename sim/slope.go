@@ -0,0 +1,142 @@
+package sim
+
+import (
+	"strings"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// Slope cells are walkable floors only: they are not solid from the sides or below.
+// "Up" slopes rise to the right; 22.5° slopes take two cells, Low then High.
+const (
+	CellSlopeUp45 Cell = iota + CellWater + 1
+	CellSlopeDown45
+	CellSlopeUp22Low
+	CellSlopeUp22High
+	CellSlopeDown22High
+	CellSlopeDown22Low
+)
+
+// floor height (fraction of the cell, from its bottom) at the left and right edges
+var slopeHeights = map[Cell][2]float64{
+	CellSlopeUp45:       {0, 1},
+	CellSlopeDown45:     {1, 0},
+	CellSlopeUp22Low:    {0, 0.5},
+	CellSlopeUp22High:   {0.5, 1},
+	CellSlopeDown22High: {1, 0.5},
+	CellSlopeDown22Low:  {0.5, 0},
+}
+
+// SlopesByIdentifier maps the tileset enum tag values, the custom data
+// {"slope": "..."} values and the IntGrid identifiers (case insensitive) to slopes.
+var SlopesByIdentifier = map[string]Cell{
+	"slope_up_45":        CellSlopeUp45,
+	"slope_down_45":      CellSlopeDown45,
+	"slope_up_22_low":    CellSlopeUp22Low,
+	"slope_up_22_high":   CellSlopeUp22High,
+	"slope_down_22_high": CellSlopeDown22High,
+	"slope_down_22_low":  CellSlopeDown22Low,
+}
+
+func init() {
+	for id, cell := range SlopesByIdentifier {
+		CellsByIdentifier[id] = cell
+	}
+}
+
+// IsSlope reports whether the cell is a slope
+func (c Cell) IsSlope() bool {
+	_, ok := slopeHeights[c]
+	return ok
+}
+
+// mirrored returns the slope flipped horizontally
+func (c Cell) mirrored() Cell {
+	switch c {
+	case CellSlopeUp45:
+		return CellSlopeDown45
+	case CellSlopeDown45:
+		return CellSlopeUp45
+	case CellSlopeUp22Low:
+		return CellSlopeDown22Low
+	case CellSlopeDown22Low:
+		return CellSlopeUp22Low
+	case CellSlopeUp22High:
+		return CellSlopeDown22High
+	case CellSlopeDown22High:
+		return CellSlopeUp22High
+	}
+	return c
+}
+
+// tileSlope returns the slope of a tile, from its tileset enum tags or custom data.
+// Flipped tiles are mirrored; vertically flipped slopes (ceilings) are solid.
+func tileSlope(meta *TileMeta, tile *ldtkgo.Tile) (Cell, bool) {
+	if meta == nil {
+		return CellEmpty, false
+	}
+	cell, ok := CellEmpty, false
+	for _, enum := range meta.Tags {
+		if cell, ok = SlopesByIdentifier[strings.ToLower(enum)]; ok {
+			break
+		}
+	}
+	if slope := meta.String("slope", ""); !ok && slope != "" {
+		cell, ok = SlopesByIdentifier[strings.ToLower(slope)]
+		if !ok {
+			cell, ok = SlopesByIdentifier["slope_"+strings.ToLower(slope)]
+		}
+	}
+	if !ok {
+		return CellEmpty, false
+	}
+	if tile.FlipY() {
+		return CellSolid, true
+	}
+	if tile.FlipX() {
+		cell = cell.mirrored()
+	}
+	return cell, true
+}
+
+// SlopeSurface returns the floor y of the slope cell (cx, cy) at the world x.
+func (g *CollisionGrid) SlopeSurface(cx, cy int, x float64) (float64, bool) {
+	heights, ok := slopeHeights[g.Cell(cx, cy)]
+	if !ok {
+		return 0, false
+	}
+	cs := float64(g.CellSize)
+	fx := (x - float64(cx)*cs) / cs
+	fx = min(max(fx, 0), 1)
+	h := heights[0] + (heights[1]-heights[0])*fx
+	return float64(cy+1)*cs - h*cs, true
+}
+
+// GroundBelow looks for the floor under the box (x, y, w, h) within maxDist
+// pixels: slopes under the bottom center, solid cells and platforms under the
+// whole width. It returns the floor y.
+func (g *CollisionGrid) GroundBelow(x, y, w, h, maxDist float64) (float64, bool) {
+	bottom := y + h
+	cx := g.ToCell(x + w/2)
+	best, found := 0., false
+	for cy := g.ToCell(bottom - collisionEps); cy <= g.ToCell(bottom+maxDist); cy++ {
+		if surface, ok := g.SlopeSurface(cx, cy, x+w/2); ok && surface >= bottom-collisionEps && surface <= bottom+maxDist {
+			best, found = surface, true
+			break
+		}
+		top := float64(cy * g.CellSize)
+		if top < bottom-collisionEps || top > bottom+maxDist {
+			continue
+		}
+		for c := g.ToCell(x); c <= g.ToCell(x+w-collisionEps); c++ {
+			if g.IsSolid(c, cy) || g.IsPlatform(c, cy) {
+				best, found = top, true
+				break
+			}
+		}
+		if found {
+			break
+		}
+	}
+	return best, found
+}
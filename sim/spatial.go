@@ -0,0 +1,161 @@
+package sim
+
+import "math"
+
+// size of the spatial hash cells, in collision grid cells
+const SpatialHashCells = 4
+
+// ----------------------------------------------------------------------------- SpatialHash struct
+// SpatialHash is the broad phase of the entity collisions: the items are
+// registered with their bounds in every cell they cover, so a query only looks
+// at the items of the cells around it instead of all of them.
+// Query results are in registration order, so the simulation stays deterministic.
+type SpatialHash[T comparable] struct {
+	CellSize float64
+	cells    map[Vec2D[int]][]T
+	bounds   map[T]Rect[float64]
+	order    map[T]int // registration number
+	next     int
+}
+
+// NewSpatialHash makes a hash of cellSize pixels cells, usually the level grid
+// size (CollisionGrid.CellSize) times SpatialHashCells.
+func NewSpatialHash[T comparable](cellSize float64) *SpatialHash[T] {
+	return &SpatialHash[T]{
+		CellSize: cellSize,
+		cells:    map[Vec2D[int]][]T{},
+		bounds:   map[T]Rect[float64]{},
+		order:    map[T]int{},
+	}
+}
+
+// Len returns the number of registered items
+func (h *SpatialHash[T]) Len() int {
+	return len(h.bounds)
+}
+
+// Bounds returns the registered bounds of the item
+func (h *SpatialHash[T]) Bounds(item T) (Rect[float64], bool) {
+	r, ok := h.bounds[item]
+	return r, ok
+}
+
+// Insert registers the item, or moves it when it is already registered.
+func (h *SpatialHash[T]) Insert(item T, r Rect[float64]) {
+	if old, ok := h.bounds[item]; ok {
+		if h.cellRange(old) == h.cellRange(r) {
+			// same cells, nothing to move
+			h.bounds[item] = r
+			return
+		}
+		h.unlink(item, old)
+	} else {
+		h.order[item] = h.next
+		h.next += 1
+	}
+	h.bounds[item] = r
+	h.eachCell(r, func(c Vec2D[int]) {
+		h.cells[c] = append(h.cells[c], item)
+	})
+}
+
+// Update is Insert for an item that moved
+func (h *SpatialHash[T]) Update(item T, r Rect[float64]) {
+	h.Insert(item, r)
+}
+
+// Remove unregisters the item
+func (h *SpatialHash[T]) Remove(item T) {
+	r, ok := h.bounds[item]
+	if !ok {
+		return
+	}
+	h.unlink(item, r)
+	delete(h.bounds, item)
+	delete(h.order, item)
+}
+
+// remove the item from the cells covered by r
+func (h *SpatialHash[T]) unlink(item T, r Rect[float64]) {
+	h.eachCell(r, func(c Vec2D[int]) {
+		items := h.cells[c]
+		for i, it := range items {
+			if it == item {
+				items = append(items[:i], items[i+1:]...)
+				break
+			}
+		}
+		if len(items) == 0 {
+			delete(h.cells, c)
+		} else {
+			h.cells[c] = items
+		}
+	})
+}
+
+// Clear unregisters all the items
+func (h *SpatialHash[T]) Clear() {
+	h.cells = map[Vec2D[int]][]T{}
+	h.bounds = map[T]Rect[float64]{}
+	h.order = map[T]int{}
+	h.next = 0
+}
+
+// Query returns the items whose bounds intersect r
+func (h *SpatialHash[T]) Query(r Rect[float64]) []T {
+	found := []T{}
+	seen := map[T]bool{}
+	h.eachCell(r, func(c Vec2D[int]) {
+		for _, item := range h.cells[c] {
+			if !seen[item] && h.bounds[item].Intersects(r) {
+				seen[item] = true
+				found = append(found, item)
+			}
+		}
+	})
+	h.sort(found)
+	return found
+}
+
+// QueryPoint returns the items whose bounds contain p
+func (h *SpatialHash[T]) QueryPoint(p Vec2D[float64]) []T {
+	found := []T{}
+	for _, item := range h.cells[h.cell(p)] {
+		if h.bounds[item].Contains(p) {
+			found = append(found, item)
+		}
+	}
+	h.sort(found)
+	return found
+}
+
+func (h *SpatialHash[T]) cell(p Vec2D[float64]) Vec2D[int] {
+	return Vec2D[int]{int(math.Floor(p.X / h.CellSize)), int(math.Floor(p.Y / h.CellSize))}
+}
+
+// the first and last cells covered by r
+func (h *SpatialHash[T]) cellRange(r Rect[float64]) [2]Vec2D[int] {
+	last := Vec2D[float64]{r.Right() - collisionEps, r.Bottom() - collisionEps}
+	if r.Empty() {
+		last = r.Pos()
+	}
+	return [2]Vec2D[int]{h.cell(r.Pos()), h.cell(last)}
+}
+
+func (h *SpatialHash[T]) eachCell(r Rect[float64], fn func(c Vec2D[int])) {
+	cr := h.cellRange(r)
+	for y := cr[0].Y; y <= cr[1].Y; y++ {
+		for x := cr[0].X; x <= cr[1].X; x++ {
+			fn(Vec2D[int]{x, y})
+		}
+	}
+}
+
+// registration order (insertion sort: query results are short)
+func (h *SpatialHash[T]) sort(items []T) {
+	for i := 1; i < len(items); i++ {
+		for j := i; j > 0 && h.order[items[j]] < h.order[items[j-1]]; j-- {
+			items[j], items[j-1] = items[j-1], items[j]
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package sim
+
+import (
+	"slices"
+	"testing"
+)
+
+// testHash registers the named boxes in a hash of 64 pixel cells
+func testHash(boxes map[string]Rect[float64], names ...string) *SpatialHash[string] {
+	h := NewSpatialHash[string](64)
+	for _, name := range names {
+		h.Insert(name, boxes[name])
+	}
+	return h
+}
+
+func TestSpatialHashQuery(t *testing.T) {
+	boxes := map[string]Rect[float64]{
+		"a":    {10, 10, 20, 20},
+		"span": {50, 50, 30, 30}, // over the corner of 4 cells
+		"far":  {200, 200, 10, 10},
+		"neg":  {-40, -10, 20, 20},
+	}
+	h := testHash(boxes, "far", "span", "a", "neg")
+	tests := []struct {
+		name string
+		r    Rect[float64]
+		want []string
+	}{
+		{"cell", Rect[float64]{0, 0, 64, 64}, []string{"span", "a"}},
+		{"registration order", Rect[float64]{0, 0, 256, 256}, []string{"far", "span", "a"}},
+		{"other side of the span", Rect[float64]{70, 70, 4, 4}, []string{"span"}},
+		{"same cell, no overlap", Rect[float64]{35, 5, 10, 10}, []string{}},
+		{"touching edges", Rect[float64]{30, 10, 10, 10}, []string{}},
+		{"negative cells", Rect[float64]{-64, -64, 64, 64}, []string{"neg"}},
+		{"empty", Rect[float64]{1000, 1000, 64, 64}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.Query(tt.r); !slices.Equal(got, tt.want) {
+				t.Errorf("query %v: %v, want %v", tt.r, got, tt.want)
+			}
+		})
+	}
+	if got := h.QueryPoint(Vec2D[float64]{75, 75}); !slices.Equal(got, []string{"span"}) {
+		t.Errorf("query point: %v, want [span]", got)
+	}
+}
+
+func TestSpatialHashMove(t *testing.T) {
+	boxes := map[string]Rect[float64]{
+		"a": {10, 10, 20, 20},
+		"b": {100, 10, 20, 20},
+	}
+	h := testHash(boxes, "a", "b")
+
+	// inside its cell
+	h.Update("a", Rect[float64]{30, 30, 20, 20})
+	if got := h.Query(Rect[float64]{10, 10, 10, 10}); len(got) != 0 {
+		t.Errorf("found %v at the old bounds", got)
+	}
+	if got := h.Query(Rect[float64]{45, 45, 1, 1}); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("found %v at the new bounds, want [a]", got)
+	}
+
+	// over a cell boundary, then out of the first cell
+	h.Update("a", Rect[float64]{60, 10, 20, 20})
+	if got := h.Query(Rect[float64]{64, 0, 64, 64}); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("spanning: %v, want [a b]", got)
+	}
+	h.Update("a", Rect[float64]{140, 140, 20, 20})
+	if got := h.Query(Rect[float64]{0, 0, 128, 64}); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("moved out: %v, want [b]", got)
+	}
+	if got := h.Query(Rect[float64]{128, 128, 64, 64}); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("moved in: %v, want [a]", got)
+	}
+	if n := len(h.cells); n != 2 {
+		t.Errorf("%d cells in use, want 2", n)
+	}
+
+	h.Remove("a")
+	h.Remove("a")
+	if h.Len() != 1 {
+		t.Errorf("%d items after removing a, want 1", h.Len())
+	}
+	if got := h.Query(Rect[float64]{0, 0, 256, 256}); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("after removing a: %v, want [b]", got)
+	}
+	h.Remove("b")
+	if len(h.cells) != 0 {
+		t.Errorf("cells left after removing everything: %v", h.cells)
+	}
+}
@@ -0,0 +1,199 @@
+package sim
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// ----------------------------------------------------------------------------- TileMeta struct
+// TileMeta is the metadata of a tileset tile set in LDtk: its enum tags and its
+// custom data, parsed when it is a JSON object. The accessors are nil-safe.
+type TileMeta struct {
+	Tileset string // identifier
+	ID      int
+	Tags    []string
+	Raw     string                 // custom data
+	Data    map[string]interface{} // nil when the custom data is not a JSON object
+}
+
+// NewTileMeta reads the metadata of the tile, nil when it has none
+func NewTileMeta(ts *ldtkgo.Tileset, id int) *TileMeta {
+	if ts == nil {
+		return nil
+	}
+	tags, raw := ts.EnumsForTile(id), ts.CustomDataForTile(id)
+	if len(tags) == 0 && raw == "" {
+		return nil
+	}
+	m := &TileMeta{Tileset: ts.Identifier, ID: id, Tags: tags, Raw: raw}
+	if json.Unmarshal([]byte(raw), &m.Data) != nil {
+		m.Data = nil
+	}
+	return m
+}
+
+// HasTag reports whether the tile has the enum tag (case insensitive)
+func (m *TileMeta) HasTag(tag string) bool {
+	if m == nil {
+		return false
+	}
+	for _, t := range m.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Decode unmarshals the custom data JSON into v
+func (m *TileMeta) Decode(v interface{}) error {
+	if m == nil || m.Raw == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(m.Raw), v)
+}
+
+func (m *TileMeta) value(key string) (interface{}, bool) {
+	if m == nil {
+		return nil, false
+	}
+	v, ok := m.Data[key]
+	return v, ok
+}
+
+func (m *TileMeta) String(key, def string) string {
+	if v, ok := m.value(key); ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return def
+}
+
+func (m *TileMeta) Float(key string, def float64) float64 {
+	if v, ok := m.value(key); ok {
+		if f, ok := v.(float64); ok {
+			return f
+		}
+	}
+	return def
+}
+
+func (m *TileMeta) Int(key string, def int) int {
+	return int(m.Float(key, float64(def)))
+}
+
+func (m *TileMeta) Bool(key string, def bool) bool {
+	if v, ok := m.value(key); ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return def
+}
+
+// ----------------------------------------------------------------------------- LevelTiles struct
+// PlacedTile is a tile of a level layer, with its metadata (nil when it has none)
+type PlacedTile struct {
+	Layer *ldtkgo.Layer
+	Tile  *ldtkgo.Tile
+	Meta  *TileMeta
+}
+
+// LevelTiles indexes the tiles of the level by cell, to query their metadata
+// by world position
+type LevelTiles struct {
+	layers []tileLayer // top layer first
+	meta   map[*ldtkgo.Tileset]map[int]*TileMeta
+}
+
+type tileLayer struct {
+	layer *ldtkgo.Layer
+	cells map[[2]int][]*ldtkgo.Tile // by layer cell (without the layer offset), drawing order
+}
+
+func NewLevelTiles(level *ldtkgo.Level) *LevelTiles {
+	lt := &LevelTiles{meta: map[*ldtkgo.Tileset]map[int]*TileMeta{}}
+	for _, layer := range level.Layers {
+		if layer.Type == ldtkgo.LayerTypeEntity || layer.GridSize <= 0 {
+			continue
+		}
+		tl := tileLayer{layer: layer, cells: map[[2]int][]*ldtkgo.Tile{}}
+		for _, tile := range layer.AllTiles() {
+			cell := [2]int{tile.Position[0] / layer.GridSize, tile.Position[1] / layer.GridSize}
+			tl.cells[cell] = append(tl.cells[cell], tile)
+		}
+		if len(tl.cells) > 0 {
+			lt.layers = append(lt.layers, tl)
+		}
+	}
+	return lt
+}
+
+// Meta returns the metadata of the tileset tile, nil when it has none
+func (lt *LevelTiles) Meta(ts *ldtkgo.Tileset, id int) *TileMeta {
+	if ts == nil {
+		return nil
+	}
+	metas, ok := lt.meta[ts]
+	if !ok {
+		metas = map[int]*TileMeta{}
+		lt.meta[ts] = metas
+	}
+	m, ok := metas[id]
+	if !ok {
+		m = NewTileMeta(ts, id)
+		metas[id] = m
+	}
+	return m
+}
+
+// TilesAt returns the tiles at the world position, top first
+func (lt *LevelTiles) TilesAt(x, y float64) []PlacedTile {
+	tiles := []PlacedTile{}
+	for _, tl := range lt.layers {
+		// the tile positions are in the layer, moved by its offset in the level
+		size := float64(tl.layer.GridSize)
+		lx, ly := x-float64(tl.layer.OffsetX), y-float64(tl.layer.OffsetY)
+		cell := [2]int{int(math.Floor(lx / size)), int(math.Floor(ly / size))}
+		stack := tl.cells[cell]
+		for i := len(stack) - 1; i >= 0; i-- {
+			tiles = append(tiles, PlacedTile{Layer: tl.layer, Tile: stack[i], Meta: lt.Meta(tl.layer.Tileset, stack[i].ID)})
+		}
+	}
+	return tiles
+}
+
+// MetaAt returns the metadata of the top tile having some at the world position
+func (lt *LevelTiles) MetaAt(x, y float64) *TileMeta {
+	for _, t := range lt.TilesAt(x, y) {
+		if t.Meta != nil {
+			return t.Meta
+		}
+	}
+	return nil
+}
+
+// HasTagAt reports whether a tile at the world position has the enum tag
+func (lt *LevelTiles) HasTagAt(x, y float64, tag string) bool {
+	for _, t := range lt.TilesAt(x, y) {
+		if t.Meta.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaterialAt returns the material of the surface at the world position (for
+// the footsteps): the "material" custom data of the top tile setting it
+func (lt *LevelTiles) MaterialAt(x, y float64) string {
+	for _, t := range lt.TilesAt(x, y) {
+		if m := t.Meta.String("material", ""); m != "" {
+			return m
+		}
+	}
+	return ""
+}
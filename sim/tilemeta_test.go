@@ -0,0 +1,35 @@
+package sim
+
+import (
+	"testing"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+func TestLevelTilesOffset(t *testing.T) {
+	layer := &ldtkgo.Layer{
+		Type:     ldtkgo.LayerTypeTile,
+		GridSize: 16,
+		OffsetX:  8,
+		OffsetY:  -16,
+		Tiles:    []*ldtkgo.Tile{{Position: []int{16, 32}, ID: 7}},
+	}
+	lt := NewLevelTiles(&ldtkgo.Level{Layers: []*ldtkgo.Layer{layer}})
+	tests := []struct {
+		x, y float64
+		want bool
+	}{
+		// the tile covers 24..40 x 16..32 in the level
+		{24, 16, true},
+		{39.9, 31.9, true},
+		{16, 32, false},
+		{23.9, 20, false},
+		{30, 32, false},
+	}
+	for _, tt := range tests {
+		tiles := lt.TilesAt(tt.x, tt.y)
+		if got := len(tiles) == 1 && tiles[0].Tile.ID == 7; got != tt.want {
+			t.Errorf("TilesAt(%g, %g) = %v, want the tile %v", tt.x, tt.y, tiles, tt.want)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package sim
+
+import (
+	"math"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------- easings
+// Easing maps the progress t of a tween, from 0 to 1, to the eased progress
+// (which may overshoot for the elastic and back easings)
+type Easing func(t float64) float64
+
+const (
+	backOvershoot = 1.70158
+	bounceN       = 7.5625
+	bounceD       = 2.75
+)
+
+func Linear(t float64) float64 { return t }
+
+func QuadIn(t float64) float64    { return t * t }
+func QuadOut(t float64) float64   { return 1 - QuadIn(1-t) }
+func QuadInOut(t float64) float64 { return inOut(QuadIn, t) }
+
+func CubicIn(t float64) float64    { return t * t * t }
+func CubicOut(t float64) float64   { return 1 - CubicIn(1-t) }
+func CubicInOut(t float64) float64 { return inOut(CubicIn, t) }
+
+func ElasticIn(t float64) float64 {
+	if t == 0 || t == 1 {
+		return t
+	}
+	return -math.Pow(2, 10*t-10) * math.Sin((t*10-10.75)*2*math.Pi/3)
+}
+func ElasticOut(t float64) float64   { return 1 - ElasticIn(1-t) }
+func ElasticInOut(t float64) float64 { return inOut(ElasticIn, t) }
+
+func BackIn(t float64) float64    { return t * t * ((backOvershoot+1)*t - backOvershoot) }
+func BackOut(t float64) float64   { return 1 - BackIn(1-t) }
+func BackInOut(t float64) float64 { return inOut(BackIn, t) }
+
+func BounceOut(t float64) float64 {
+	switch {
+	case t < 1/bounceD:
+		return bounceN * t * t
+	case t < 2/bounceD:
+		t -= 1.5 / bounceD
+		return bounceN*t*t + 0.75
+	case t < 2.5/bounceD:
+		t -= 2.25 / bounceD
+		return bounceN*t*t + 0.9375
+	}
+	t -= 2.625 / bounceD
+	return bounceN*t*t + 0.984375
+}
+func BounceIn(t float64) float64    { return 1 - BounceOut(1-t) }
+func BounceInOut(t float64) float64 { return inOut(BounceIn, t) }
+
+// inOut runs the in easing for the first half and its mirror for the second one
+func inOut(in Easing, t float64) float64 {
+	if t < 0.5 {
+		return in(2*t) / 2
+	}
+	return 1 - in(2-2*t)/2
+}
+
+// Easings by name, for the LDtk fields and the config (case insensitive)
+var Easings = map[string]Easing{
+	"linear":       Linear,
+	"quadin":       QuadIn,
+	"quadout":      QuadOut,
+	"quadinout":    QuadInOut,
+	"cubicin":      CubicIn,
+	"cubicout":     CubicOut,
+	"cubicinout":   CubicInOut,
+	"elasticin":    ElasticIn,
+	"elasticout":   ElasticOut,
+	"elasticinout": ElasticInOut,
+	"backin":       BackIn,
+	"backout":      BackOut,
+	"backinout":    BackInOut,
+	"bouncein":     BounceIn,
+	"bounceout":    BounceOut,
+	"bounceinout":  BounceInOut,
+}
+
+// EasingByName returns the easing, "quad_in_out" or "QuadInOut"
+func EasingByName(name string) (Easing, bool) {
+	e, ok := Easings[strings.ToLower(strings.ReplaceAll(name, "_", ""))]
+	return e, ok
+}
+
+// ----------------------------------------------------------------------------- Animator
+// Animator is stepped once per tick by Tweens until done
+type Animator interface {
+	// Update steps one tick, true when done
+	Update() bool
+	// Reset starts over (in a sequence played again)
+	Reset()
+}
+
+// ----------------------------------------------------------------------------- Tween struct
+// Tween sets an eased value going from From to To in Ticks ticks
+type Tween struct {
+	From, To   float64
+	Ticks      int
+	Ease       Easing
+	Set        func(v float64)
+	Yoyo       bool // back to From after reaching To
+	Repeat     int  // plays after the first one, -1 forever
+	OnComplete func()
+	tick       int
+	played     int
+	back       bool
+}
+
+func NewTween(set func(float64), from, to float64, ticks int, ease Easing) *Tween {
+	if ease == nil {
+		ease = Linear
+	}
+	return &Tween{From: from, To: to, Ticks: max(ticks, 1), Ease: ease, Set: set}
+}
+
+// TweenVec tweens a position (or size) from the from to the to vector
+func TweenVec(set func(Vec2D[float64]), from, to Vec2D[float64], ticks int, ease Easing) *Tween {
+	return NewTween(func(t float64) { set(from.Lerp(to, t)) }, 0, 1, ticks, ease)
+}
+
+// SetYoyo, SetRepeat and Then are set-and-return helpers
+func (t *Tween) SetYoyo() *Tween {
+	t.Yoyo = true
+	return t
+}
+
+func (t *Tween) SetRepeat(n int) *Tween {
+	t.Repeat = n
+	return t
+}
+
+func (t *Tween) Then(fn func()) *Tween {
+	t.OnComplete = fn
+	return t
+}
+
+// Value returns the current value
+func (t *Tween) Value() float64 {
+	p := float64(t.tick) / float64(t.Ticks)
+	if t.back {
+		p = 1 - p
+	}
+	return t.From + (t.To-t.From)*t.Ease(p)
+}
+
+func (t *Tween) Update() bool {
+	t.tick += 1
+	if t.Set != nil {
+		t.Set(t.Value())
+	}
+	if t.tick < t.Ticks {
+		return false
+	}
+	t.tick = 0
+	if t.Yoyo && !t.back {
+		t.back = true
+		return false
+	}
+	t.back = false
+	t.played += 1
+	if t.Repeat < 0 || t.played <= t.Repeat {
+		return false
+	}
+	if t.OnComplete != nil {
+		t.OnComplete()
+	}
+	return true
+}
+
+func (t *Tween) Reset() {
+	t.tick, t.played, t.back = 0, 0, false
+}
+
+// ----------------------------------------------------------------------------- Sequence struct
+// Sequence plays its steps one after the other
+type Sequence struct {
+	Steps      []Animator
+	OnComplete func()
+	current    int
+}
+
+func NewSequence(steps ...Animator) *Sequence {
+	return &Sequence{Steps: steps}
+}
+
+func (s *Sequence) Update() bool {
+	for s.current < len(s.Steps) {
+		if !s.Steps[s.current].Update() {
+			return false
+		}
+		s.current += 1
+		// the next step starts on the next tick
+		if s.current < len(s.Steps) {
+			return false
+		}
+	}
+	if s.OnComplete != nil {
+		s.OnComplete()
+	}
+	return true
+}
+
+func (s *Sequence) Reset() {
+	s.current = 0
+	for _, step := range s.Steps {
+		step.Reset()
+	}
+}
+
+// ----------------------------------------------------------------------------- Parallel struct
+// Parallel plays its steps together, done with the last one
+type Parallel struct {
+	Steps      []Animator
+	OnComplete func()
+	done       []bool
+}
+
+func NewParallel(steps ...Animator) *Parallel {
+	return &Parallel{Steps: steps, done: make([]bool, len(steps))}
+}
+
+func (p *Parallel) Update() bool {
+	all := true
+	for i, step := range p.Steps {
+		if !p.done[i] {
+			p.done[i] = step.Update()
+		}
+		all = all && p.done[i]
+	}
+	if all && p.OnComplete != nil {
+		p.OnComplete()
+	}
+	return all
+}
+
+func (p *Parallel) Reset() {
+	for i, step := range p.Steps {
+		p.done[i] = false
+		step.Reset()
+	}
+}
+
+// ----------------------------------------------------------------------------- Timer struct
+// Timer calls Fn after Ticks ticks, every Ticks ticks when it repeats
+type Timer struct {
+	Ticks  int
+	Repeat bool
+	Fn     func()
+	tick   int
+}
+
+func (t *Timer) Update() bool {
+	t.tick += 1
+	if t.tick < t.Ticks {
+		return false
+	}
+	t.tick = 0
+	if t.Fn != nil {
+		t.Fn()
+	}
+	return !t.Repeat
+}
+
+func (t *Timer) Reset() {
+	t.tick = 0
+}
+
+// Wait is a sequence step doing nothing for the ticks
+func Wait(ticks int) *Timer {
+	return &Timer{Ticks: ticks}
+}
+
+// ----------------------------------------------------------------------------- Tweens struct
+// Tweens runs the tweens, sequences and timers; the game steps it in its
+// update, so they pause with it
+type Tweens struct {
+	active   []Animator
+	updating bool
+	removed  map[Animator]bool // while updating
+	cleared  bool
+}
+
+func NewTweens() *Tweens {
+	return &Tweens{}
+}
+
+// Add starts the animator, returned to be stopped with Remove
+func (ts *Tweens) Add(a Animator) Animator {
+	ts.active = append(ts.active, a)
+	return a
+}
+
+// Tween starts a tween
+func (ts *Tweens) Tween(set func(float64), from, to float64, ticks int, ease Easing) *Tween {
+	t := NewTween(set, from, to, ticks, ease)
+	ts.Add(t)
+	return t
+}
+
+// After calls fn once after the ticks
+func (ts *Tweens) After(ticks int, fn func()) *Timer {
+	t := &Timer{Ticks: ticks, Fn: fn}
+	ts.Add(t)
+	return t
+}
+
+// Every calls fn every ticks, until removed
+func (ts *Tweens) Every(ticks int, fn func()) *Timer {
+	t := &Timer{Ticks: ticks, Fn: fn, Repeat: true}
+	ts.Add(t)
+	return t
+}
+
+// Remove stops the animator, without completing it
+func (ts *Tweens) Remove(a Animator) {
+	if ts.updating {
+		ts.removed[a] = true
+	}
+	for i, active := range ts.active {
+		if active == a {
+			ts.active = append(ts.active[:i:i], ts.active[i+1:]...)
+			return
+		}
+	}
+}
+
+func (ts *Tweens) Clear() {
+	ts.active = nil
+	ts.cleared = ts.updating
+}
+
+func (ts *Tweens) Len() int {
+	return len(ts.active)
+}
+
+// Update steps all the animators one tick, the done ones are removed. The ones
+// added while updating start on the next tick.
+func (ts *Tweens) Update() {
+	active := ts.active
+	ts.active = nil
+	ts.updating, ts.removed, ts.cleared = true, map[Animator]bool{}, false
+	for _, a := range active {
+		if ts.cleared || ts.removed[a] {
+			continue
+		}
+		if a.Update() {
+			ts.removed[a] = true
+		}
+	}
+	kept := []Animator{}
+	for _, a := range active {
+		if !ts.cleared && !ts.removed[a] {
+			kept = append(kept, a)
+		}
+	}
+	ts.active = append(kept, ts.active...)
+	ts.updating, ts.removed = false, nil
+}
@@ -0,0 +1,132 @@
+package sim
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestEasingEndpoints(t *testing.T) {
+	names := []string{}
+	for name := range Easings {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		ease := Easings[name]
+		t.Run(name, func(t *testing.T) {
+			if got := ease(0); !almostEqual(got, 0) {
+				t.Errorf("%s(0) = %v, want 0", name, got)
+			}
+			if got := ease(1); !almostEqual(got, 1) {
+				t.Errorf("%s(1) = %v, want 1", name, got)
+			}
+		})
+	}
+}
+
+func TestTweenPlays(t *testing.T) {
+	tests := []struct {
+		name   string
+		yoyo   bool
+		repeat int
+		want   []float64
+	}{
+		{"once", false, 0, []float64{1, 2, 3, 4}},
+		{"yoyo", true, 0, []float64{1, 2, 3, 4, 3, 2, 1, 0}},
+		{"repeat", false, 2, []float64{1, 2, 3, 4, 1, 2, 3, 4, 1, 2, 3, 4}},
+		{"yoyo repeat", true, 1, []float64{1, 2, 3, 4, 3, 2, 1, 0, 1, 2, 3, 4, 3, 2, 1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []float64{}
+			completed := 0
+			tw := NewTween(func(v float64) { got = append(got, v) }, 0, 4, 4, Linear)
+			tw.Then(func() { completed += 1 }).SetRepeat(tt.repeat)
+			if tt.yoyo {
+				tw.SetYoyo()
+			}
+			for i := 0; i < 100 && !tw.Update(); i++ {
+			}
+			if !slices.EqualFunc(got, tt.want, almostEqual) {
+				t.Errorf("values %v, want %v", got, tt.want)
+			}
+			if completed != 1 {
+				t.Errorf("completed %d times, want 1", completed)
+			}
+		})
+	}
+}
+
+func TestSequenceOrder(t *testing.T) {
+	tick := 0
+	got := []string{}
+	log := func(name string) func() {
+		return func() { got = append(got, fmt.Sprintf("%s@%d", name, tick)) }
+	}
+	s := NewSequence(
+		&Timer{Ticks: 2, Fn: log("a")},
+		NewTween(nil, 0, 1, 1, Linear).Then(log("b")),
+		&Timer{Ticks: 3, Fn: log("c")},
+	)
+	s.OnComplete = log("done")
+	for tick = 1; tick <= 100 && !s.Update(); tick++ {
+	}
+	// each step starts on the tick after the previous one is done
+	want := []string{"a@2", "b@3", "c@6", "done@6"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got = got[:0]
+	s.Reset()
+	for tick = 1; tick <= 100 && !s.Update(); tick++ {
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("after reset, got %v, want %v", got, want)
+	}
+}
+
+// TestTweensChangedWhileUpdating changes the running animators from the
+// OnComplete of a tween, before or after another (repeating) one in the list
+func TestTweensChangedWhileUpdating(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   bool // the other one runs before the change
+		change   func(ts *Tweens, other Animator, count func())
+		wantRuns int // of the other ones, in 2 ticks
+		wantLen  int
+	}{
+		{"remove later", false, func(ts *Tweens, other Animator, _ func()) { ts.Remove(other) }, 0, 0},
+		{"remove earlier", true, func(ts *Tweens, other Animator, _ func()) { ts.Remove(other) }, 1, 0},
+		{"clear later", false, func(ts *Tweens, _ Animator, _ func()) { ts.Clear() }, 0, 0},
+		{"clear earlier", true, func(ts *Tweens, _ Animator, _ func()) { ts.Clear() }, 1, 0},
+		// the added one starts on the next tick
+		{"add", false, func(ts *Tweens, _ Animator, count func()) { ts.Every(1, count) }, 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := NewTweens()
+			runs := 0
+			count := func() { runs += 1 }
+			other := &Timer{Ticks: 1, Repeat: true, Fn: count}
+			change := NewTween(nil, 0, 1, 1, Linear)
+			change.Then(func() { tt.change(ts, other, count) })
+			if tt.before {
+				ts.Add(other)
+			}
+			ts.Add(change)
+			if !tt.before {
+				ts.Add(other)
+			}
+			ts.Update()
+			ts.Update()
+			if runs != tt.wantRuns {
+				t.Errorf("other ones ran %d times, want %d", runs, tt.wantRuns)
+			}
+			if ts.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", ts.Len(), tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package sim
+
+import "math"
+
+// Vec2D is a 2D vector (or point) of ints or floats. The operations return new
+// vectors; the ones that need fractions (Length, Normalize, Rotate...) always
+// work in float64.
+type Vec2D[T int | float64] struct {
+	X, Y T
+}
+
+func NewVec2D[T int | float64](x, y T) Vec2D[T] {
+	return Vec2D[T]{x, y}
+}
+
+func (v Vec2D[T]) Add(other Vec2D[T]) Vec2D[T] {
+	return Vec2D[T]{v.X + other.X, v.Y + other.Y}
+}
+
+// AddDelta adds delta to both coordinates
+func (v Vec2D[T]) AddDelta(delta T) Vec2D[T] {
+	return Vec2D[T]{v.X + delta, v.Y + delta}
+}
+
+func (v Vec2D[T]) Sub(other Vec2D[T]) Vec2D[T] {
+	return Vec2D[T]{v.X - other.X, v.Y - other.Y}
+}
+
+func (v Vec2D[T]) Scale(k T) Vec2D[T] {
+	return Vec2D[T]{v.X * k, v.Y * k}
+}
+
+func (v Vec2D[T]) Neg() Vec2D[T] {
+	return Vec2D[T]{-v.X, -v.Y}
+}
+
+func (v Vec2D[T]) Dot(other Vec2D[T]) T {
+	return v.X*other.X + v.Y*other.Y
+}
+
+// Cross is the z of the 3D cross product: > 0 when other is clockwise from v
+// (the y axis pointing down)
+func (v Vec2D[T]) Cross(other Vec2D[T]) T {
+	return v.X*other.Y - v.Y*other.X
+}
+
+func (v Vec2D[T]) IsZero() bool {
+	return v.X == 0 && v.Y == 0
+}
+
+func (v Vec2D[T]) Length() float64 {
+	return math.Hypot(float64(v.X), float64(v.Y))
+}
+
+func (v Vec2D[T]) LengthSquared() T {
+	return v.X*v.X + v.Y*v.Y
+}
+
+func (v Vec2D[T]) Distance(other Vec2D[T]) float64 {
+	return other.Sub(v).Length()
+}
+
+// Normalize returns the unit vector of the same direction; the zero vector stays zero
+func (v Vec2D[T]) Normalize() Vec2D[float64] {
+	l := v.Length()
+	if l == 0 {
+		return Vec2D[float64]{}
+	}
+	return Vec2D[float64]{float64(v.X) / l, float64(v.Y) / l}
+}
+
+// Lerp interpolates linearly from v (t = 0) to other (t = 1)
+func (v Vec2D[T]) Lerp(other Vec2D[T], t float64) Vec2D[float64] {
+	a, b := v.Float(), other.Float()
+	return Vec2D[float64]{a.X + (b.X-a.X)*t, a.Y + (b.Y-a.Y)*t}
+}
+
+// Rotate rotates the vector by angle radians (clockwise on screen, as ebiten.GeoM.Rotate)
+func (v Vec2D[T]) Rotate(angle float64) Vec2D[float64] {
+	co, si := math.Cos(angle), math.Sin(angle)
+	x, y := float64(v.X), float64(v.Y)
+	return Vec2D[float64]{co*x - si*y, si*x + co*y}
+}
+
+// Angle is the direction of the vector in radians
+func (v Vec2D[T]) Angle() float64 {
+	return math.Atan2(float64(v.Y), float64(v.X))
+}
+
+// ----------------------------------------------------------------------------- conversions
+func (v Vec2D[T]) Float() Vec2D[float64] {
+	return Vec2D[float64]{float64(v.X), float64(v.Y)}
+}
+
+// Int rounds the coordinates down (so -0.5 is in the pixel/cell -1, not 0)
+func (v Vec2D[T]) Int() Vec2D[int] {
+	return Vec2D[int]{int(math.Floor(float64(v.X))), int(math.Floor(float64(v.Y)))}
+}
+
+func (v Vec2D[T]) Round() Vec2D[int] {
+	return Vec2D[int]{int(math.Round(float64(v.X))), int(math.Round(float64(v.Y)))}
+}
+
+func (v Vec2D[T]) XY() (T, T) {
+	return v.X, v.Y
+}
@@ -0,0 +1,86 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b Vec2D[float64]) bool {
+	const eps = 1e-9
+	return math.Abs(a.X-b.X) < eps && math.Abs(a.Y-b.Y) < eps
+}
+
+func TestVecNormalize(t *testing.T) {
+	tests := []struct {
+		v    Vec2D[float64]
+		want Vec2D[float64]
+	}{
+		{Vec2D[float64]{}, Vec2D[float64]{}},
+		{Vec2D[float64]{3, 4}, Vec2D[float64]{0.6, 0.8}},
+		{Vec2D[float64]{0, -2}, Vec2D[float64]{0, -1}},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Normalize(); !near(got, tt.want) {
+			t.Errorf("%v.Normalize() = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+	if got := NewVec2D(0, 0).Normalize(); !got.IsZero() {
+		t.Errorf("int zero vector normalized to %v", got)
+	}
+}
+
+func TestVecRotate(t *testing.T) {
+	tests := []struct {
+		v     Vec2D[float64]
+		angle float64
+		want  Vec2D[float64]
+	}{
+		{Vec2D[float64]{1, 0}, 0, Vec2D[float64]{1, 0}},
+		// clockwise on screen, y down
+		{Vec2D[float64]{1, 0}, math.Pi / 2, Vec2D[float64]{0, 1}},
+		{Vec2D[float64]{0, 1}, math.Pi / 2, Vec2D[float64]{-1, 0}},
+		{Vec2D[float64]{2, 3}, math.Pi, Vec2D[float64]{-2, -3}},
+		{Vec2D[float64]{1, 1}, -math.Pi / 4, Vec2D[float64]{math.Sqrt2, 0}},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Rotate(tt.angle); !near(got, tt.want) {
+			t.Errorf("%v.Rotate(%g) = %v, want %v", tt.v, tt.angle, got, tt.want)
+		}
+	}
+}
+
+func TestVecLerp(t *testing.T) {
+	a, b := Vec2D[float64]{-2, 4}, Vec2D[float64]{6, -8}
+	tests := []struct {
+		t    float64
+		want Vec2D[float64]
+	}{
+		{0, a},
+		{1, b},
+		{0.5, Vec2D[float64]{2, -2}},
+	}
+	for _, tt := range tests {
+		if got := a.Lerp(b, tt.t); !near(got, tt.want) {
+			t.Errorf("Lerp(%g) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestVecInt(t *testing.T) {
+	tests := []struct {
+		v     Vec2D[float64]
+		floor Vec2D[int]
+		round Vec2D[int]
+	}{
+		{Vec2D[float64]{1.5, 2.4}, Vec2D[int]{1, 2}, Vec2D[int]{2, 2}},
+		{Vec2D[float64]{-0.5, -1.6}, Vec2D[int]{-1, -2}, Vec2D[int]{-1, -2}},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Int(); got != tt.floor {
+			t.Errorf("%v.Int() = %v, want %v", tt.v, got, tt.floor)
+		}
+		if got := tt.v.Round(); got != tt.round {
+			t.Errorf("%v.Round() = %v, want %v", tt.v, got, tt.round)
+		}
+	}
+}
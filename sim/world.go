@@ -0,0 +1,310 @@
+// Package sim holds the game simulation state (player, collision grid) separated
+// from rendering, so the game logic can be stepped headless, without Ebiten.
+package sim
+
+import (
+	"github.com/solarlune/ldtkgo"
+)
+
+// Input is the state of the game controls for one tick.
+type Input struct {
+	Left, Right bool
+	Up, Down    bool
+	Jump        bool
+}
+
+// ----------------------------------------------------------------------------- World struct
+// World is the whole simulation state of the current level.
+type World struct {
+	Level       *ldtkgo.Level
+	Grid        *CollisionGrid
+	Tiles       *LevelTiles // the tiles metadata, by world position
+	Paths       *Pathfinder
+	Player      *Player
+	Platforms   []*Platform
+	Enemies     []*Enemy
+	Pickups     []*Pickup
+	Checkpoints []*Checkpoint
+	Doors       []*Door
+	SpawnPoints []*SpawnPoint
+	Triggers    []*Trigger
+	Spawn       Vec2D[float64] // player position after a death (the last checkpoint)
+	Tick        int64
+	DeadTicks   int   // ticks since the player died, 0 while alive
+	HoldRespawn bool  // the dead player waits to respawn, e.g. until the screen is covered
+	RespawnedAt int64 // tick of the last respawn (-1 before any)
+	// broad phase of the entity collisions: the active dynamic solids and enemies,
+	// the pickups not collected yet, the checkpoints not activated yet, the doors
+	// and the triggers
+	Solids          *SpatialHash[*Solid]
+	Actors          *SpatialHash[*Enemy]
+	Items           *SpatialHash[*Pickup]
+	CheckpointZones *SpatialHash[*Checkpoint]
+	DoorZones       *SpatialHash[*Door]
+	TriggerZones    *SpatialHash[*Trigger]
+	// the world events (see events.go); the game replaces it with its own bus
+	Events *EventBus
+	// the zones the player touched in the last tick
+	atDoors    []*Door
+	inTriggers []*Trigger
+	upHeld     bool
+}
+
+// NewWorld builds the world of the level; defs (from OpenDefs) give the meaning
+// of the IntGrid values, nil means all solid.
+func NewWorld(level *ldtkgo.Level, defs *Defs) *World {
+	w := &World{
+		Level:       level,
+		Grid:        NewCollisionGrid(level, defs),
+		Tiles:       NewLevelTiles(level),
+		Player:      NewPlayer(),
+		RespawnedAt: -1,
+		Events:      NewEventBus(),
+	}
+	w.Pickups, w.Checkpoints = NewItems(level)
+	w.Items = NewSpatialHash[*Pickup](float64(w.Grid.CellSize * SpatialHashCells))
+	for _, p := range w.Pickups {
+		w.Items.Insert(p, p.Rect)
+	}
+	w.CheckpointZones = NewSpatialHash[*Checkpoint](w.Items.CellSize)
+	for _, c := range w.Checkpoints {
+		w.CheckpointZones.Insert(c, c.Rect)
+	}
+	w.Spawn = w.Player.Pos
+	w.newZones(defs)
+	w.Paths = NewPathfinder(w.Grid)
+	w.resetEntities()
+	return w
+}
+
+// resetEntities (re)builds the dynamic entities from their LDtk state
+func (w *World) resetEntities() {
+	for _, p := range w.Platforms {
+		p.unblock(w.Paths)
+	}
+	w.Platforms = NewPlatforms(w.Level)
+	w.Enemies = NewEnemies(w.Level)
+	w.Solids = NewSpatialHash[*Solid](w.Items.CellSize)
+	for _, p := range w.Platforms {
+		w.Solids.Insert(&p.Solid, p.Solid.Rect)
+		p.block(w.Paths)
+	}
+	w.Actors = NewSpatialHash[*Enemy](w.Items.CellSize)
+	for _, e := range w.Enemies {
+		w.Actors.Insert(e, e.Rect)
+	}
+}
+
+// Respawn brings the player back to life at the spawn point (the last
+// checkpoint) and resets the platforms and enemies. The collected pickups and
+// the activated checkpoints stay.
+func (w *World) Respawn() {
+	physics := w.Player.Physics
+	*w.Player = *NewPlayer()
+	w.Player.Physics = physics
+	w.Player.Pos = w.Spawn
+	w.resetEntities()
+	w.DeadTicks = 0
+	w.HoldRespawn = false
+	w.RespawnedAt = w.Tick
+	w.Events.Publish(PlayerRespawned{Pos: w.Player.Pos})
+}
+
+// death: ticks from the death to the respawn (at least), and how far below the level
+// bottom things are removed
+const (
+	DeathTicks      = 90
+	KillPlaneMargin = 32.0
+)
+
+// Update steps the simulation by one tick.
+func (w *World) Update(in Input) {
+	// the dynamic solids move first, then carry the player
+	for _, p := range w.Platforms {
+		p.Update(w.Player.Riding == &p.Solid, float64(w.Level.Height))
+		p.block(w.Paths)
+		if p.Solid.Active {
+			w.Solids.Update(&p.Solid, p.Solid.Rect)
+		} else {
+			w.Solids.Remove(&p.Solid)
+		}
+	}
+	for _, e := range w.Enemies {
+		was := e.State
+		e.Update(w.Player, w.Paths)
+		w.publishEnemy(e, was)
+		if e.Active {
+			w.Actors.Update(e, e.Rect)
+		} else {
+			w.Actors.Remove(e)
+		}
+	}
+	// kill plane: falling out of the level
+	levelBottom := float64(w.Level.Height) + KillPlaneMargin
+	for _, e := range w.Enemies {
+		if e.Active && e.Y > levelBottom {
+			e.Active = false
+			w.Actors.Remove(e)
+		}
+	}
+
+	if w.Player.Dead() {
+		w.Player.Update(in, w.Grid, nil)
+		w.DeadTicks += 1
+		if w.DeadTicks >= DeathTicks && !w.HoldRespawn {
+			w.Respawn()
+		}
+		w.Tick += 1
+		return
+	}
+	was := *w.Player
+	w.Player.Update(in, w.Grid, w.Solids.Query(w.playerReach()))
+	w.touchEnemies(was.Bounds().Bottom())
+	w.touchItems()
+	w.touchZones(in)
+	if w.Player.Bounds().Y > levelBottom {
+		w.Player.Kill()
+	}
+	w.publishPlayer(&was)
+	w.Tick += 1
+}
+
+// publishPlayer publishes what happened to the player this tick, was is its
+// state before the tick
+func (w *World) publishPlayer(was *Player) {
+	p := w.Player
+	if p.Jumped {
+		w.Events.Publish(PlayerJumped{Pos: p.Pos})
+	}
+	if p.Grounded && !was.Grounded {
+		feet := p.Bounds()
+		w.Events.Publish(PlayerLanded{Pos: p.Pos, Speed: was.Velocity.Y, Material: w.Tiles.MaterialAt(feet.Center().X, feet.Bottom()+1)})
+	}
+	if p.Health < was.Health {
+		w.Events.Publish(PlayerHit{Pos: p.Pos, Health: p.Health})
+	}
+	if p.Dead() {
+		w.Events.Publish(PlayerDied{Pos: p.Pos})
+	}
+}
+
+// touchEnemies stomps the enemies the player falls on, the other ones hurt it
+func (w *World) touchEnemies(prevBottom float64) {
+	p := w.Player
+	for _, e := range w.Actors.Query(p.Bounds()) {
+		if !e.Harmful() {
+			continue
+		}
+		falling := p.Bounds().Bottom() > prevBottom || p.Velocity.Y > 0
+		if e.Stompable && falling && prevBottom <= e.Y+StompMargin {
+			was := e.State
+			e.Hurt(p.Bounds().Center().X)
+			w.publishEnemy(e, was)
+			p.Bounce(e.Y)
+			continue
+		}
+		p.HurtFrom(e.Center().X)
+	}
+}
+
+func (w *World) publishEnemy(e *Enemy, was EnemyState) {
+	if e.State != was {
+		w.Events.Publish(EnemyChanged{Enemy: e, From: was})
+	}
+}
+
+// touchItems collects the pickups and activates the checkpoints the player touches
+func (w *World) touchItems() {
+	if w.Player.Dead() {
+		return
+	}
+	box := w.Player.Bounds()
+	for _, p := range w.Items.Query(box) {
+		p.Collected = true
+		p.CollectedAt = w.Tick
+		w.Items.Remove(p)
+		w.Events.Publish(ItemCollected{Level: w.Level.Identifier, Pickup: p})
+	}
+	for _, c := range w.CheckpointZones.Query(box) {
+		c.Active = true
+		w.CheckpointZones.Remove(c)
+		w.Spawn = c.Spawn()
+		w.Events.Publish(CheckpointReached{Level: w.Level.Identifier, Checkpoint: c})
+	}
+}
+
+// Progress returns the number of pickups collected and their total
+func (w *World) Progress() (int, int) {
+	n := 0
+	for _, p := range w.Pickups {
+		if p.Collected {
+			n += 1
+		}
+	}
+	return n, len(w.Pickups)
+}
+
+// Restore marks the pickups already collected and the checkpoints already
+// activated (from a save, or a previous visit of the level), without scoring
+// them again. The respawn point is the last activated checkpoint.
+func (w *World) Restore(collected, checkpoints []string) {
+	for _, id := range collected {
+		for _, p := range w.Pickups {
+			if p.ID == id && !p.Collected {
+				p.Collected = true
+				p.CollectedAt = -1
+				w.Items.Remove(p)
+			}
+		}
+	}
+	for _, id := range checkpoints {
+		for _, c := range w.Checkpoints {
+			if c.ID == id {
+				c.Active = true
+				w.CheckpointZones.Remove(c)
+				w.Spawn = c.Spawn()
+			}
+		}
+	}
+}
+
+// playerReach is the area the player can touch in one tick: its hitbox grown
+// by a grid cell, more than it can move (see PlayerPhysics.maxFallSpeed)
+func (w *World) playerReach() Rect[float64] {
+	return w.Player.Bounds().Grow(float64(w.Grid.CellSize))
+}
+
+// ----------------------------------------------------------------------------- headless runner
+// Script returns the scripted input for the given tick.
+type Script func(tick int64) Input
+
+// Hold is a script holding the same input for every tick.
+func Hold(in Input) Script {
+	return func(int64) Input { return in }
+}
+
+// Run steps the world n ticks feeding it the scripted input (no input when script is nil).
+func (w *World) Run(n int, script Script) {
+	for i := 0; i < n; i++ {
+		w.Update(w.input(script))
+	}
+}
+
+// RunUntil steps the world at most n ticks, stopping as soon as cond holds.
+// It returns the number of ticks run and whether cond was met.
+func (w *World) RunUntil(n int, script Script, cond func(*World) bool) (int, bool) {
+	for i := 0; i < n; i++ {
+		if cond(w) {
+			return i, true
+		}
+		w.Update(w.input(script))
+	}
+	return n, cond(w)
+}
+
+func (w *World) input(script Script) Input {
+	if script == nil {
+		return Input{}
+	}
+	return script(w.Tick)
+}
@@ -0,0 +1,360 @@
+package sim
+
+import (
+	"math"
+	"testing"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+const testMap = "../assets/map/map1.ldtk"
+
+// testWorld loads the world of the level of the test map
+func testWorld(t *testing.T, identifier string) *World {
+	t.Helper()
+	project, err := ldtkgo.Open(testMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := OpenDefs(testMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	level := project.LevelByIdentifier(identifier)
+	if level == nil {
+		t.Fatalf("no level %s", identifier)
+	}
+	return NewWorld(level, defs)
+}
+
+// testGrid builds a grid of 16 pixel cells from rows of '#' (solid), '=' (one
+// way platform), '^' (hazard), 'H' (ladder), '~' (water), '/' and '\\' (45°
+// slopes up and down), '*' (ice) and '.' (empty)
+func testGrid(rows ...string) *CollisionGrid {
+	g := &CollisionGrid{Width: len(rows[0]), Height: len(rows), CellSize: 16}
+	g.cells = make([]Cell, g.Width*g.Height)
+	cells := map[rune]Cell{'#': CellSolid, '=': CellOneWay, '^': CellHazard, 'H': CellLadder, '~': CellWater,
+		'/': CellSlopeUp45, '\\': CellSlopeDown45, '*': CellIce}
+	for y, row := range rows {
+		for x, c := range row {
+			g.cells[y*g.Width+x] = cells[c]
+		}
+	}
+	return g
+}
+
+func TestPlayerLandsOnFloor(t *testing.T) {
+	w := testWorld(t, "Level_0")
+	if _, ok := w.RunUntil(60, nil, func(w *World) bool { return w.Player.Grounded }); !ok {
+		t.Fatalf("player not grounded after 60 ticks, at %v", w.Player.Pos)
+	}
+	if bottom := w.Player.Bounds().Bottom(); bottom > float64(w.Level.Height) {
+		t.Errorf("player landed below the level, bottom %g", bottom)
+	}
+}
+
+func TestKillPlaneDeath(t *testing.T) {
+	w := testWorld(t, "Level_0")
+	var died int
+	Subscribe(w.Events, func(PlayerDied) { died += 1 })
+	_, ok := w.RunUntil(1200, Hold(Input{Left: true}), func(w *World) bool { return w.Player.Dead() })
+	if !ok {
+		t.Fatalf("player still alive at %v", w.Player.Pos)
+	}
+	if y := w.Player.Bounds().Y; y <= float64(w.Level.Height)+KillPlaneMargin {
+		t.Errorf("player died above the kill plane, y %g", y)
+	}
+	if died != 1 {
+		t.Errorf("%d PlayerDied events, want 1", died)
+	}
+}
+
+func TestHoldRespawn(t *testing.T) {
+	w := testWorld(t, "Level_0")
+	respawned := 0
+	Subscribe(w.Events, func(PlayerRespawned) { respawned += 1 })
+	w.Run(1, nil)
+	w.Player.Kill()
+	w.HoldRespawn = true
+	w.Run(DeathTicks+10, nil)
+	if !w.Player.Dead() || respawned != 0 {
+		t.Fatalf("respawned while held (dead %v, %d events)", w.Player.Dead(), respawned)
+	}
+	w.HoldRespawn = false
+	w.Run(1, nil)
+	if w.Player.Dead() || respawned != 1 {
+		t.Errorf("not respawned once released (dead %v, %d events)", w.Player.Dead(), respawned)
+	}
+	if w.Player.Pos != w.Spawn {
+		t.Errorf("respawned at %v, want %v", w.Player.Pos, w.Spawn)
+	}
+}
+
+// playerAt is a player with the default physics at (x, y)
+func playerAt(x, y float64) *Player {
+	p := NewPlayer()
+	p.Pos = Vec2D[float64]{x, y}
+	return p
+}
+
+// the player hitbox spans the cells 0 and 1 of the test grids
+func TestIntGridCells(t *testing.T) {
+	run := func(p *Player, g *CollisionGrid, n int, in Input) {
+		for i := 0; i < n; i++ {
+			p.Update(in, g, nil)
+		}
+	}
+	bottom := func(p *Player) float64 {
+		return p.Bounds().Bottom()
+	}
+
+	t.Run("one way", func(t *testing.T) {
+		g := testGrid("....", "....", "....", "....", "==..", "....", "....", "####")
+		p := playerAt(0, 0)
+		run(p, g, 60, Input{})
+		if !p.Grounded || bottom(p) != 64 {
+			t.Fatalf("not standing on the platform: grounded %v, bottom %g", p.Grounded, bottom(p))
+		}
+		p.Velocity.Y = -p.Physics.JumpSpeed
+		run(p, g, 60, Input{})
+		if bottom(p) != 64 {
+			t.Errorf("jumped through the platform from below, bottom %g", bottom(p))
+		}
+		run(p, g, 60, Input{Down: true})
+		if !p.Grounded || bottom(p) != 112 {
+			t.Errorf("not dropped to the floor: grounded %v, bottom %g", p.Grounded, bottom(p))
+		}
+	})
+
+	t.Run("hazard", func(t *testing.T) {
+		g := testGrid("....", "....", "....", "^^^^", "####")
+		p := playerAt(0, 0)
+		run(p, g, 30, Input{})
+		if p.Health != MaxHealth-1 || p.Invulnerable == 0 {
+			t.Errorf("health %d, invulnerable %d after the spikes", p.Health, p.Invulnerable)
+		}
+	})
+
+	t.Run("ladder", func(t *testing.T) {
+		g := testGrid("....", "....", ".H..", ".H..", ".H..", ".H..", ".H..", "####")
+		p := playerAt(0, 80)
+		run(p, g, 30, Input{})
+		run(p, g, 40, Input{Up: true})
+		if !p.Climbing || p.State != Player_Climb {
+			t.Fatalf("not climbing: state %v", p.State)
+		}
+		if bottom(p) >= 112 {
+			t.Errorf("did not climb, bottom %g", bottom(p))
+		}
+		run(p, g, 60, Input{Jump: true})
+		if p.Climbing {
+			t.Error("still climbing after jumping")
+		}
+	})
+
+	t.Run("water", func(t *testing.T) {
+		g := testGrid("....", "....", "~~~~", "~~~~", "####")
+		p := playerAt(0, 0)
+		run(p, g, 20, Input{})
+		if !p.InWater || p.Velocity.Y > p.Physics.WaterMaxFall {
+			t.Errorf("in water %v, falling at %g", p.InWater, p.Velocity.Y)
+		}
+	})
+}
+
+func TestSlopes(t *testing.T) {
+	g := testGrid(
+		"..........",
+		"..........",
+		"..........",
+		"..........",
+		"..........",
+		"..........",
+		"...../####",
+		"##########",
+	)
+	p := playerAt(0, 80)
+	bottom := func() float64 {
+		return p.Bounds().Bottom()
+	}
+	p.Update(Input{}, g, nil)
+	if !p.Grounded || bottom() != 112 {
+		t.Fatalf("not on the floor: bottom %g", bottom())
+	}
+	onSlope := 0
+	for i := 0; i < 60; i++ {
+		p.Update(Input{Right: true}, g, nil)
+		if surface, ok := g.SlopeSurface(5, 6, p.Bounds().Center().X); ok && p.OnSlope {
+			onSlope += 1
+			if !almostEqual(bottom(), surface) {
+				t.Fatalf("tick %d: feet at %g on the slope surface %g", i, bottom(), surface)
+			}
+		}
+	}
+	if onSlope == 0 {
+		t.Error("never walked on the slope")
+	}
+	if !p.Grounded || bottom() != 96 {
+		t.Fatalf("not on the plateau: grounded %v, bottom %g, at %v", p.Grounded, bottom(), p.Pos)
+	}
+	// back down the slope without leaving the ground
+	for i := 0; i < 60; i++ {
+		p.Update(Input{Left: true}, g, nil)
+		if !p.Grounded {
+			t.Fatalf("tick %d: left the ground at %v", i, p.Pos)
+		}
+	}
+	if bottom() != 112 {
+		t.Errorf("not back on the floor: bottom %g", bottom())
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestJumpForgiveness(t *testing.T) {
+	g := testGrid(
+		"..........",
+		"..........",
+		"..........",
+		"..........",
+		"####......",
+		"..........",
+		"..........",
+		"##########",
+	)
+	pp := DefaultPlayerPhysics()
+	// ticks to fall from the ledge to the floor, when the jump is pressed in the air
+	land := 0
+	p := playerAt(80, 32)
+	for ; !p.Grounded; land++ {
+		p.Update(Input{}, g, nil)
+	}
+
+	tests := []struct {
+		name   string
+		x      float64                    // start on the ledge, or falling to the floor past it
+		script func(tick, left int) Input // left: tick the player left the ledge, -1 before
+		jumps  bool
+	}{
+		{"coyote", 16, coyoteJump(pp.CoyoteTicks - 2), true},
+		{"coyote over", 16, coyoteJump(pp.CoyoteTicks + 2), false},
+		{"buffered", 80, bufferedJump(land - pp.JumpBufferTicks + 2), true},
+		{"buffered too early", 80, bufferedJump(land - pp.JumpBufferTicks - 2), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := playerAt(tt.x, 32)
+			left, jumped := -1, false
+			for tick := 0; tick < 120; tick++ {
+				p.Update(tt.script(tick, left), g, nil)
+				if left < 0 && !p.Grounded && p.Velocity.Y >= 0 {
+					left = tick
+				}
+				jumped = jumped || p.Velocity.Y < -pp.JumpSpeed/2
+			}
+			if jumped != tt.jumps {
+				t.Errorf("jumped %v, want %v", jumped, tt.jumps)
+			}
+		})
+	}
+
+	t.Run("jump cut", func(t *testing.T) {
+		apex := func(held int) float64 {
+			p := playerAt(80, 80)
+			top := p.Pos.Y
+			for tick := 0; tick < 120; tick++ {
+				p.Update(Input{Jump: tick < held}, g, nil)
+				top = min(top, p.Pos.Y)
+			}
+			return top
+		}
+		full, cut := apex(120), apex(4)
+		if cut <= full {
+			t.Errorf("cut jump apex %g not below the full jump apex %g", cut, full)
+		}
+	})
+}
+
+// coyoteJump walks off the ledge and presses jump some ticks after leaving it
+func coyoteJump(after int) func(tick, left int) Input {
+	return func(tick, left int) Input {
+		return Input{Right: left < 0, Jump: left >= 0 && tick == left+after}
+	}
+}
+
+// bufferedJump presses jump at the tick, falling to the floor
+func bufferedJump(at int) func(tick, left int) Input {
+	return func(tick, left int) Input {
+		return Input{Jump: tick == at}
+	}
+}
+
+func TestHorizontalFriction(t *testing.T) {
+	pp := DefaultPlayerPhysics()
+	tests := []struct {
+		name  string
+		floor string
+		accel float64 // speed gained or lost per tick on the floor
+	}{
+		{"ground", "##########", pp.GroundAccel},
+		{"ice", "**********", pp.GroundAccel * pp.IceFriction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGrid("..........", "..........", tt.floor)
+			p := playerAt(0, 0)
+			for !p.Grounded {
+				p.Update(Input{}, g, nil)
+			}
+			p.Update(Input{Right: true}, g, nil)
+			if !almostEqual(p.Velocity.X, tt.accel) {
+				t.Errorf("speed %g after one tick, want %g", p.Velocity.X, tt.accel)
+			}
+			for i := 0; i < 100; i++ {
+				p.Update(Input{Right: true}, g, nil)
+			}
+			if p.Velocity.X != pp.MoveSpeed {
+				t.Errorf("top speed %g, want %g", p.Velocity.X, pp.MoveSpeed)
+			}
+			// release: slow down to a stop, slower on ice
+			ticks := 0
+			for ; p.Velocity.X > 0 && ticks < 100; ticks++ {
+				p.Update(Input{}, g, nil)
+			}
+			decel := pp.GroundDecel
+			if tt.floor[0] == '*' {
+				decel *= pp.IceFriction
+			}
+			if want := int(math.Ceil(pp.MoveSpeed / decel)); ticks != want {
+				t.Errorf("stopped in %d ticks, want %d", ticks, want)
+			}
+		})
+	}
+}
+
+func TestCheckpointZones(t *testing.T) {
+	c := &Checkpoint{Rect: Rect[float64]{100, 0, 16, 32}, ID: "cp"}
+	w := &World{
+		Level:           &ldtkgo.Level{Identifier: "Level_0"},
+		Player:          NewPlayer(),
+		Checkpoints:     []*Checkpoint{c},
+		Items:           NewSpatialHash[*Pickup](64),
+		CheckpointZones: NewSpatialHash[*Checkpoint](64),
+		Events:          NewEventBus(),
+	}
+	w.CheckpointZones.Insert(c, c.Rect)
+	reached := 0
+	Subscribe(w.Events, func(CheckpointReached) { reached += 1 })
+	for i := 0; i < 3; i++ {
+		w.Player.Pos = Vec2D[float64]{100 - HitboxX, 10 - HitboxY}
+		w.touchItems()
+	}
+	if !c.Active || reached != 1 || w.Spawn != c.Spawn() {
+		t.Errorf("active %v, reached %d times, spawn %v", c.Active, reached, w.Spawn)
+	}
+	if w.CheckpointZones.Len() != 0 {
+		t.Error("the activated checkpoint is still in the broad phase")
+	}
+}
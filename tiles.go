@@ -0,0 +1,157 @@
+package main
+
+import (
+	"image"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/solarlune/ldtkgo"
+
+	"gogopixel/sim"
+)
+
+// default duration of an animated tile frame
+const TileFrameMs = 150
+
+// ----------------------------------------------------------------------------- TileAnimation struct
+// TileAnimation is the frame sequence of an animated tile (water, lava, flags),
+// set in the LDtk tileset on its first tile:
+//
+//	custom data  {"animation": {"frames": [48, 49, 50], "duration": 100}}
+//	             frames: tile IDs, or "count": N for the tile and the next N-1 ones
+//	             of its row; duration: ms per frame (TileFrameMs by default)
+//	enum tag     AnimN (e.g. Anim4): the tile and the next N-1 ones, TileFrameMs each
+type TileAnimation struct {
+	Frames     []int // tile IDs
+	FrameTicks int
+}
+
+type tileAnimationData struct {
+	Animation *struct {
+		Frames   []int `json:"frames"`
+		Count    int   `json:"count"`
+		Duration int   `json:"duration"`
+	} `json:"animation"`
+}
+
+// Frame returns the tile ID to draw at the tick
+func (a *TileAnimation) Frame(tick int64) int {
+	return a.Frames[int(tick/int64(a.FrameTicks))%len(a.Frames)]
+}
+
+func msToTicks(ms int) int {
+	return max(1, ms*ebiten.DefaultTPS/1000)
+}
+
+func consecutiveTiles(id, count int) []int {
+	frames := make([]int, count)
+	for i := range frames {
+		frames[i] = id + i
+	}
+	return frames
+}
+
+// tileAnimation reads the animation of the tile from its metadata, nil when
+// it is static; invalid animations are logged and static
+func tileAnimation(m *sim.TileMeta) *TileAnimation {
+	if m == nil {
+		return nil
+	}
+	if m.Data != nil {
+		var d tileAnimationData
+		if err := m.Decode(&d); err != nil {
+			log.Printf("tileset %s tile %d: static, invalid animation: %v", m.Tileset, m.ID, err)
+			return nil
+		}
+		if d.Animation != nil {
+			a := &TileAnimation{Frames: d.Animation.Frames, FrameTicks: msToTicks(TileFrameMs)}
+			if len(a.Frames) == 0 {
+				a.Frames = consecutiveTiles(m.ID, max(d.Animation.Count, 1))
+			}
+			if d.Animation.Duration > 0 {
+				a.FrameTicks = msToTicks(d.Animation.Duration)
+			}
+			return a
+		}
+	}
+	for _, tag := range m.Tags {
+		if n, ok := strings.CutPrefix(tag, "Anim"); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 1 {
+				log.Printf("tileset %s tile %d: static, invalid animation enum tag %s", m.Tileset, m.ID, tag)
+				return nil
+			}
+			return &TileAnimation{Frames: consecutiveTiles(m.ID, count), FrameTicks: msToTicks(TileFrameMs)}
+		}
+	}
+	return nil
+}
+
+// tileSrc returns the tileset image area of the tile ID
+func tileSrc(ts *ldtkgo.Tileset, id int) image.Rectangle {
+	step := ts.GridSize + ts.Spacing
+	cols := (ts.Width - 2*ts.Padding + ts.Spacing) / step
+	x := ts.Padding + id%cols*step
+	y := ts.Padding + id/cols*step
+	return image.Rect(x, y, x+ts.GridSize, y+ts.GridSize)
+}
+
+// ----------------------------------------------------------------------------- AnimatedTile struct
+// AnimatedTile is a level tile drawn over the static layers, not baked in Offscreen
+type AnimatedTile struct {
+	Tile      *ldtkgo.Tile
+	Layer     *ldtkgo.Layer
+	Animation *TileAnimation
+	Alpha     float32
+}
+
+// animation returns the animation of the tile, nil if it is static
+func (er *Renderer) animation(ts *ldtkgo.Tileset, id int) *TileAnimation {
+	anims, ok := er.Animations[ts.Path]
+	if !ok {
+		anims = map[int]*TileAnimation{}
+		er.Animations[ts.Path] = anims
+	}
+	a, ok := anims[id]
+	if !ok {
+		a = tileAnimation(er.Tiles.Meta(ts, id))
+		anims[id] = a
+	}
+	return a
+}
+
+// TileAt returns the metadata of the top tile having some at the world
+// position, nil when there is none (see sim.LevelTiles for the other queries)
+func (er *Renderer) TileAt(pos sim.Vec2D[float64]) *sim.TileMeta {
+	return er.Tiles.MetaAt(pos.XY())
+}
+
+// tileOptions places the tile of the layer in the level, flipped
+func tileOptions(tile *ldtkgo.Tile, layer *ldtkgo.Layer) *ebiten.DrawImageOptions {
+	opt := &ebiten.DrawImageOptions{}
+	opt.GeoM.Translate(float64(-layer.GridSize/2), float64(-layer.GridSize/2))
+	if tile.FlipX() {
+		opt.GeoM.Scale(-1, 1)
+	}
+	if tile.FlipY() {
+		opt.GeoM.Scale(1, -1)
+	}
+	opt.GeoM.Translate(float64(layer.GridSize/2), float64(layer.GridSize/2))
+	opt.GeoM.Translate(float64(tile.Position[0]), float64(tile.Position[1]))
+	return opt
+}
+
+// DrawAnimatedTiles draws the current frame of the animated tiles through the camera
+func (er *Renderer) DrawAnimatedTiles(screen *ebiten.Image) {
+	cam := er.camera.GeoM()
+	for _, t := range er.AnimatedTiles {
+		ts := t.Layer.Tileset
+		src := tileSrc(ts, t.Animation.Frame(er.Tick))
+		opt := tileOptions(t.Tile, t.Layer)
+		opt.GeoM.Concat(cam)
+		opt.ColorScale.ScaleAlpha(t.Alpha)
+		screen.DrawImage(er.Tilesets[ts.Path].SubImage(src).(*ebiten.Image), opt)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"gogopixel/sim"
+)
+
+// ----------------------------------------------------------------------------- TransitionKind
+type TransitionKind int
+
+const (
+	Transition_Fade         TransitionKind = iota // to the color and back
+	Transition_Wipe                               // covers from the left, uncovers to the right
+	Transition_DiagonalWipe                       // the same from the top-left corner
+	Transition_Iris                               // closing circle on the player, then opening
+)
+
+func (k TransitionKind) String() string {
+	return [...]string{"Fade", "Wipe", "DiagonalWipe", "Iris"}[k]
+}
+
+// ParseTransition reads a transition kind name ("fade", "wipe", "diagonal_wipe", "iris")
+func ParseTransition(name string) (TransitionKind, error) {
+	n := strings.ReplaceAll(strings.ToLower(name), "_", "")
+	for k := Transition_Fade; k <= Transition_Iris; k++ {
+		if strings.ToLower(k.String()) == n {
+			return k, nil
+		}
+	}
+	return Transition_Fade, fmt.Errorf("unknown transition %q", name)
+}
+
+// ----------------------------------------------------------------------------- Transition struct
+// Transition covers the screen in Ticks ticks, calls OnMidpoint (where the
+// level swap happens), stays covered while held, then uncovers it in Ticks ticks
+type Transition struct {
+	Kind  TransitionKind
+	Color color.RGBA
+	Ticks int
+	Pause bool // the world is paused until it is done
+	Hold  bool // covered after the midpoint until released
+	// the iris center on the screen, the screen center when nil
+	Center     func() sim.Vec2D[float64]
+	OnMidpoint func()
+	OnDone     func()
+	tick       int
+}
+
+// Covered returns how much of the screen is covered, from 0 to 1
+func (t *Transition) Covered() float64 {
+	if t.tick <= t.Ticks {
+		return float64(t.tick) / float64(t.Ticks)
+	}
+	return 2 - float64(t.tick)/float64(t.Ticks)
+}
+
+// Covering reports whether the transition is still before its midpoint
+func (t *Transition) Covering() bool {
+	return t.tick < t.Ticks
+}
+
+// Release lets a held transition uncover the screen
+func (t *Transition) Release() {
+	if t != nil {
+		t.Hold = false
+	}
+}
+
+// Update steps the transition one tick, true when done
+func (t *Transition) Update() bool {
+	if t.tick == t.Ticks && t.Hold {
+		return false
+	}
+	t.tick += 1
+	if t.tick == t.Ticks && t.OnMidpoint != nil {
+		t.OnMidpoint()
+	}
+	if t.tick < 2*t.Ticks {
+		return false
+	}
+	if t.OnDone != nil {
+		t.OnDone()
+	}
+	return true
+}
+
+// Draw draws the transition over the final screen
+func (t *Transition) Draw(screen *ebiten.Image) {
+	c := float32(t.Covered())
+	if c <= 0 {
+		return
+	}
+	b := screen.Bounds()
+	w, h := float32(b.Dx()), float32(b.Dy())
+	path := &vector.Path{}
+	switch t.Kind {
+	case Transition_Fade:
+		clr := t.Color
+		clr.A = uint8(float32(clr.A) * c)
+		vector.DrawFilledRect(screen, 0, 0, w, h, clr, false)
+		return
+	case Transition_Wipe:
+		x0, x1 := float32(0), w*c
+		if !t.Covering() {
+			x0, x1 = w*(1-c), w
+		}
+		vector.DrawFilledRect(screen, x0, 0, x1-x0, h, t.Color, false)
+		return
+	case Transition_DiagonalWipe:
+		// the edge is the x + y = d line
+		d := (w + h) * c
+		if t.Covering() {
+			path.MoveTo(0, 0)
+			path.LineTo(d, 0)
+			path.LineTo(0, d)
+		} else {
+			d = (w + h) * (1 - c)
+			path.MoveTo(d, 0)
+			path.LineTo(w+h, 0)
+			path.LineTo(w+h, w+h)
+			path.LineTo(0, w+h)
+			path.LineTo(0, d)
+		}
+		path.Close()
+	case Transition_Iris:
+		center := sim.NewVec2D(float64(w)/2, float64(h)/2)
+		if t.Center != nil {
+			center = t.Center()
+		}
+		// the farthest screen corner is just outside the open iris
+		far := 0.
+		for _, corner := range []sim.Vec2D[float64]{sim.NewVec2D(0., 0.), sim.NewVec2D(float64(w), 0), sim.NewVec2D(0, float64(h)), sim.NewVec2D(float64(w), float64(h))} {
+			far = math.Max(far, corner.Distance(center))
+		}
+		path.MoveTo(0, 0)
+		path.LineTo(w, 0)
+		path.LineTo(w, h)
+		path.LineTo(0, h)
+		path.Close()
+		if r := float32(far) * (1 - c); r > 0 {
+			path.Arc(float32(center.X), float32(center.Y), r, 0, 2*math.Pi, vector.Clockwise)
+			path.Close()
+		}
+	}
+	fillPath(screen, path, t.Color)
+}
+
+var whitePixel = func() *ebiten.Image {
+	img := ebiten.NewImage(3, 3)
+	img.Fill(color.White)
+	return img
+}()
+
+// fillPath fills the path with the even-odd rule (the inner subpaths are holes)
+func fillPath(screen *ebiten.Image, path *vector.Path, clr color.RGBA) {
+	vs, is := path.AppendVerticesAndIndicesForFilling(nil, nil)
+	for i := range vs {
+		vs[i].SrcX, vs[i].SrcY = 1, 1
+		vs[i].ColorR = float32(clr.R) / 0xff
+		vs[i].ColorG = float32(clr.G) / 0xff
+		vs[i].ColorB = float32(clr.B) / 0xff
+		vs[i].ColorA = float32(clr.A) / 0xff
+	}
+	op := &ebiten.DrawTrianglesOptions{FillRule: ebiten.EvenOdd, AntiAlias: true}
+	screen.DrawTriangles(vs, is, whitePixel, op)
+}
+
+// ----------------------------------------------------------------------------- Game
+// NewTransition returns a black transition of the configured length, the iris
+// on the player
+func (g *Game) NewTransition(kind TransitionKind) *Transition {
+	return &Transition{
+		Kind:   kind,
+		Color:  color.RGBA{0, 0, 0, 0xff},
+		Ticks:  g.config.Transitions.Ticks,
+		Center: g.playerOnScreen,
+	}
+}
+
+// StartTransition starts a screen transition, or queues it after the running ones
+func (g *Game) StartTransition(t *Transition) {
+	if g.transition != nil {
+		g.queued = append(g.queued, t)
+		return
+	}
+	g.transition = t
+}
+
+// updateTransition steps the running transition, starting the next queued one
+// when it is done
+func (g *Game) updateTransition() {
+	if g.transition == nil || !g.transition.Update() {
+		return
+	}
+	g.transition = nil
+	if len(g.queued) > 0 {
+		g.transition = g.queued[0]
+		g.queued = g.queued[1:]
+	}
+}
+
+// worldPaused reports whether a transition pauses the world
+func (g *Game) worldPaused() bool {
+	return g.transition != nil && g.transition.Pause
+}
+
+func (g *Game) playerOnScreen() sim.Vec2D[float64] {
+	return g.camera.WorldToScreen(g.world.Player.Bounds().Center())
+}
+
+// subscribeTransitions runs the transitions of the doors and the deaths
+func (g *Game) subscribeTransitions() {
+	door, _ := ParseTransition(g.config.Transitions.Door) // validated by LoadConfig
+	death, _ := ParseTransition(g.config.Transitions.Death)
+	// the level swap at the midpoint, the world paused
+	sim.Subscribe(g.events, func(e sim.DoorEntered) {
+		t := g.NewTransition(door)
+		t.Pause = true
+		t.OnMidpoint = func() { g.enterDoor(e.Door) }
+		g.StartTransition(t)
+	})
+	// the respawn waits for the screen to be covered, which stays covered
+	// until the respawn
+	var dying *Transition
+	sim.Subscribe(g.events, func(sim.PlayerDied) {
+		world := g.world
+		world.HoldRespawn = true
+		dying = g.NewTransition(death)
+		dying.Hold = true
+		dying.OnMidpoint = func() { world.HoldRespawn = false }
+		g.StartTransition(dying)
+	})
+	sim.Subscribe(g.events, func(sim.PlayerRespawned) {
+		dying.Release()
+		dying = nil
+	})
+	// or the dead player left the level (a loaded save)
+	sim.Subscribe(g.events, func(sim.LevelEntered) {
+		dying.Release()
+		dying = nil
+	})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ------------------------------------------------
+type ScaleMode int
+
+const (
+	ScaleInteger ScaleMode = iota // largest integer scale, letterbox/pillarbox bars
+	ScaleFit                      // largest scale keeping the aspect ratio, bars
+	ScaleFill                     // stretch to the whole window
+)
+
+func (m ScaleMode) String() string {
+	return [...]string{"integer", "fit", "fill"}[m]
+}
+
+func ParseScaleMode(s string) (ScaleMode, error) {
+	for _, m := range []ScaleMode{ScaleInteger, ScaleFit, ScaleFill} {
+		if m.String() == s {
+			return m, nil
+		}
+	}
+	return ScaleInteger, fmt.Errorf("unknown scale mode %q (integer, fit, fill)", s)
+}
+
+// ----------------------------------------------------------------------------- Viewport struct
+// Viewport is the fixed logical resolution the game is drawn at (Canvas),
+// scaled up to the window.
+type Viewport struct {
+	Width, Height int // logical resolution
+	Mode          ScaleMode
+	Canvas        *ebiten.Image
+	BarColor      color.Color
+	// computed by Layout
+	scaleX, scaleY float64
+	offX, offY     float64
+}
+
+func NewViewport(width, height int, mode ScaleMode) *Viewport {
+	return &Viewport{
+		Width:    width,
+		Height:   height,
+		Mode:     mode,
+		Canvas:   ebiten.NewImage(width, height),
+		BarColor: color.Black,
+		scaleX:   1.,
+		scaleY:   1.,
+	}
+}
+
+// Layout works on the real window pixels (so integer scaling stays pixel perfect
+// on HiDPI monitors) and computes where the canvas goes.
+func (v *Viewport) Layout(outsideWidth, outsideHeight int) (int, int) {
+	dsf := ebiten.DeviceScaleFactor()
+	w := int(math.Ceil(float64(outsideWidth) * dsf))
+	h := int(math.Ceil(float64(outsideHeight) * dsf))
+
+	sx := float64(w) / float64(v.Width)
+	sy := float64(h) / float64(v.Height)
+	switch v.Mode {
+	case ScaleInteger:
+		s := math.Floor(math.Min(sx, sy))
+		if s < 1 {
+			// window smaller than the logical resolution
+			s = math.Min(sx, sy)
+		}
+		sx, sy = s, s
+	case ScaleFit:
+		s := math.Min(sx, sy)
+		sx, sy = s, s
+	}
+	v.scaleX, v.scaleY = sx, sy
+	v.offX = math.Floor((float64(w) - float64(v.Width)*sx) / 2)
+	v.offY = math.Floor((float64(h) - float64(v.Height)*sy) / 2)
+
+	return w, h
+}
+
+// Draw draws the frame (the canvas, or the canvas post-processed) scaled on the
+// screen, with the bars around it
+func (v *Viewport) Draw(screen, frame *ebiten.Image) {
+	screen.Fill(v.BarColor)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(v.scaleX, v.scaleY)
+	op.GeoM.Translate(v.offX, v.offY)
+	if v.Mode != ScaleInteger {
+		op.Filter = ebiten.FilterLinear
+	}
+	screen.DrawImage(frame, op)
+}
+
+// ScreenToLogical maps screen (window) coordinates, e.g. the cursor position,
+// to logical coordinates; false when outside the canvas (on the bars).
+func (v *Viewport) ScreenToLogical(x, y int) (float64, float64, bool) {
+	lx := (float64(x) - v.offX) / v.scaleX
+	ly := (float64(y) - v.offY) / v.scaleY
+	inside := lx >= 0 && ly >= 0 && lx < float64(v.Width) && ly < float64(v.Height)
+	return lx, ly, inside
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log"
+
+	"gogopixel/sim"
+)
+
+// ticks a trigger message stays on screen, and of its slide in and out
+const (
+	MessageTicks      = 180
+	MessageSlideTicks = 20
+)
+
+// ----------------------------------------------------------------------------- trigger events
+// TriggerHandler handles the event of a trigger zone
+type TriggerHandler func(g *Game, t *sim.Trigger, phase sim.TriggerPhase)
+
+var triggerHandlers = map[string]TriggerHandler{
+	"show_message":   showMessage,
+	"start_cutscene": startCutscene,
+	"switch_music":   switchMusic,
+}
+
+func showMessage(g *Game, t *sim.Trigger, phase sim.TriggerPhase) {
+	if phase == sim.Trigger_Enter {
+		g.message = t.Arg
+		if g.messageAnim != nil {
+			g.tweens.Remove(g.messageAnim)
+		}
+		// slides in from the left, then out
+		width := float64(len(t.Arg) * DebugCharW)
+		slide := func(v float64) { g.messageX = v }
+		g.messageAnim = g.tweens.Add(sim.NewSequence(
+			sim.NewTween(slide, -width, 0, MessageSlideTicks, sim.BackOut),
+			sim.Wait(MessageTicks),
+			sim.NewTween(slide, 0, -width, MessageSlideTicks, sim.QuadIn).Then(func() {
+				g.message = ""
+				g.messageAnim = nil
+			}),
+		))
+	}
+}
+
+// there are no cutscenes yet, the game only records the one started
+func startCutscene(g *Game, t *sim.Trigger, phase sim.TriggerPhase) {
+	if phase == sim.Trigger_Enter && g.cutscene != t.Arg {
+		g.cutscene = t.Arg
+		log.Println("cutscene:", t.Arg)
+	}
+}
+
+func switchMusic(g *Game, t *sim.Trigger, phase sim.TriggerPhase) {
+	if phase == sim.Trigger_Enter {
+		g.audio.PlayMusic(t.Arg)
+	}
+}
+
+// ----------------------------------------------------------------------------- doors
+// subscribeZones handles the doors and the trigger zones of the worlds
+func (g *Game) subscribeZones() {
+	sim.Subscribe(g.events, func(e sim.TriggerFired) {
+		handler, ok := triggerHandlers[e.Trigger.Event]
+		if !ok {
+			if e.Phase == sim.Trigger_Enter {
+				log.Printf("trigger %s: unknown event %q", e.Trigger.ID, e.Trigger.Event)
+			}
+			return
+		}
+		handler(g, e.Trigger, e.Phase)
+	})
+}
+
+// enterDoor (at the midpoint of the door transition) moves the player to the door target, loading its level when needed
+func (g *Game) enterDoor(d *sim.Door) {
+	index := g.CurrentLevel
+	if d.TargetLevel != "" {
+		index = g.levelIndex(d.TargetLevel)
+		if index < 0 {
+			log.Printf("door %s: level %q not found", d.ID, d.TargetLevel)
+			return
+		}
+	}
+	health := g.world.Player.Health
+	if index != g.CurrentLevel {
+		g.LoadLevel(index)
+	}
+	pos, ok := g.world.SpawnAt(d.TargetSpawn)
+	if !ok {
+		log.Printf("door %s: spawn %q not found in %s", d.ID, d.TargetSpawn, g.world.Level.Identifier)
+		pos = g.world.Spawn
+	}
+	g.world.PlacePlayer(pos)
+	g.world.Spawn = pos
+	g.world.Player.Health = health
+}
+
+// levelIndex returns the index of the level with the identifier, -1 if not found
+func (g *Game) levelIndex(identifier string) int {
+	for i, level := range g.LDTKProject.Levels {
+		if level.Identifier == identifier {
+			return i
+		}
+	}
+	return -1
+}